@@ -0,0 +1,431 @@
+// Package analytics 将小程序数据分析接口的数据按天导出为CSV或JSONL文件
+package analytics
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/amazing-gao/applet/api"
+)
+
+const (
+	// FormatCSV 导出为CSV文件，新文件会写入表头
+	FormatCSV Format = "csv"
+	// FormatJSONL 导出为JSONL文件，每行一条记录
+	FormatJSONL Format = "jsonl"
+)
+
+const (
+	// errCodeInvalidToken access_token无效
+	errCodeInvalidToken = 40001
+	// errCodeExpiredToken access_token已过期
+	errCodeExpiredToken = 42001
+	// errCodeQuotaLimit 接口调用超过限额
+	errCodeQuotaLimit = 45009
+)
+
+var (
+	// beijing 数据分析接口按北京时间统计
+	beijing = time.FixedZone("CST", 8*60*60)
+
+	// checkpointLockers 断点文件 -> 锁，同一进程内共用断点文件的导出器串行读写
+	checkpointLockers       = map[string]*sync.Mutex{}
+	checkpointLockersLocker = &sync.Mutex{}
+)
+
+type (
+	// Format 导出文件格式
+	Format string
+
+	// Exporter 数据分析导出器
+	// examples:
+	// exporter := analytics.NewExporter(app.API, "./data", analytics.FormatCSV).SetCheckpoint("./data/checkpoint.json")
+	// err := exporter.Export(ctx, begin, end)
+	Exporter struct {
+		api        *api.WechatAPI
+		dir        string
+		format     Format
+		metrics    []Metric
+		interval   time.Duration // 两次请求的最小间隔
+		backoff    time.Duration // 超过接口限额时的首次退避时间，之后每次加倍
+		retries    int           // 超过接口限额时的最大重试次数
+		checkpoint string        // 断点文件，为空时不记录断点
+		lastCall   time.Time
+		locker     *sync.Mutex
+	}
+
+	// checkpoint 断点，按appid和指标族记录已导出的最后一天及导出后的文件长度
+	checkpoint struct {
+		Apps map[string]map[Metric]*progress `json:"apps"`
+	}
+
+	// progress 指标族的导出进度
+	progress struct {
+		Date   string `json:"date"`   // 已导出的最后一天，为空时尚未导出，Offset为首次导出前的文件长度
+		Offset int64  `json:"offset"` // 导出该天后的文件长度，断点之后写入的内容会在重新导出时被截断
+	}
+)
+
+// NewExporter 新建一个数据分析导出器，默认导出全部指标族，每秒最多请求一次
+// 超过接口限额时从1秒开始退避，最多重试5次
+func NewExporter(wechatAPI *api.WechatAPI, dir string, format Format) *Exporter {
+	return &Exporter{
+		api:      wechatAPI,
+		dir:      dir,
+		format:   format,
+		metrics:  AllMetrics,
+		interval: time.Second,
+		backoff:  time.Second,
+		retries:  5,
+		locker:   &sync.Mutex{},
+	}
+}
+
+// SetMetrics 设置导出的指标族
+func (exp *Exporter) SetMetrics(metrics ...Metric) *Exporter {
+	exp.metrics = metrics
+
+	return exp
+}
+
+// SetRateLimit 设置两次请求的最小间隔
+func (exp *Exporter) SetRateLimit(interval time.Duration) *Exporter {
+	exp.interval = interval
+
+	return exp
+}
+
+// SetBackoff 设置超过接口限额（errcode 45009）时的首次退避时间及最大重试次数，退避时间每次加倍
+func (exp *Exporter) SetBackoff(backoff time.Duration, retries int) *Exporter {
+	exp.backoff = backoff
+	exp.retries = retries
+
+	return exp
+}
+
+// SetCheckpoint 设置断点文件，再次导出时从断点继续
+// 同一进程内的多个导出器可共用一个断点文件，按appid分别记录，保存时只更新当前小程序的断点
+// 多个进程不能共用断点文件
+func (exp *Exporter) SetCheckpoint(filename string) *Exporter {
+	exp.checkpoint = filename
+
+	return exp
+}
+
+// Export 按天导出[begin, end]范围内的数据，文件名为{appid}_{metric}.{format}
+// 每个指标族的每一天导出成功后更新断点，已导出的日期会被跳过
+// 中断后重新导出时，文件中断点之后的内容会被截断，不会产生重复数据
+// 断点只记录已导出的最后一天，早于该天的日期同样会被跳过，补导历史数据需使用新的断点文件
+// 用户画像只能查询昨天，其他日期会被跳过
+func (exp *Exporter) Export(ctx context.Context, begin, end time.Time) error {
+	defer exp.locker.Unlock()
+	exp.locker.Lock()
+
+	for _, metric := range exp.metrics {
+		if fetchers[metric] == nil {
+			return fmt.Errorf("unsupport metric: %s", metric)
+		}
+	}
+
+	if err := os.MkdirAll(exp.dir, 0755); err != nil {
+		return err
+	}
+
+	appID := exp.api.AppID()
+	done, err := exp.loadProgress(appID)
+	if err != nil {
+		return err
+	}
+
+	yesterday := formatDay(time.Now().In(beijing).AddDate(0, 0, -1))
+	begin, end = truncateDay(begin), truncateDay(end)
+	for day := begin; !day.After(end); day = day.AddDate(0, 0, 1) {
+		for _, metric := range exp.metrics {
+			if metric == MetricUserPortrait && formatDay(day) != yesterday {
+				continue
+			}
+			if p := done[metric]; p != nil && p.Date >= formatDay(day) {
+				continue
+			}
+
+			t, err := exp.fetch(ctx, metric, day)
+			if err != nil {
+				return fmt.Errorf("export %s %s: %s", metric, formatDay(day), err)
+			}
+
+			// 未设置断点文件时追加到文件末尾
+			filename := exp.filename(appID, metric)
+			offset := int64(-1)
+			if exp.checkpoint != "" {
+				if done[metric] == nil {
+					// 首次导出该指标族时保留文件已有的内容，先记录当前长度，中断后从这里截断
+					if offset, err = fileSize(filename); err != nil {
+						return err
+					}
+					done[metric] = &progress{Offset: offset}
+					if err = exp.saveCheckpoint(appID, done); err != nil {
+						return err
+					}
+				}
+				offset = done[metric].Offset
+			}
+			if offset, err = exp.write(filename, offset, t); err != nil {
+				return err
+			}
+
+			done[metric] = &progress{Date: formatDay(day), Offset: offset}
+			if err = exp.saveCheckpoint(appID, done); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// fetch 按频率限制拉取一天的指标数据
+// access_token失效时重新生成后重试一次，超过接口限额时退避重试
+func (exp *Exporter) fetch(ctx context.Context, metric Metric, day time.Time) (*table, error) {
+	renewed, retries, backoff := false, 0, exp.backoff
+	for {
+		if err := exp.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		t, resp, errs := fetchers[metric](exp.api, day)
+		if len(errs) != 0 {
+			return nil, errs[0]
+		}
+
+		if !renewed && (resp.ErrCode == errCodeInvalidToken || resp.ErrCode == errCodeExpiredToken) {
+			renewed = true
+			if resp, errs = exp.api.RenewToken(); len(errs) != 0 {
+				return nil, errs[0]
+			} else if resp.ErrCode != 0 {
+				return nil, fmt.Errorf("renew token errcode:%d errmsg:%s", resp.ErrCode, resp.ErrMsg)
+			}
+			continue
+		}
+
+		if resp.ErrCode == errCodeQuotaLimit && retries < exp.retries {
+			retries++
+			if err := sleep(ctx, backoff); err != nil {
+				return nil, err
+			}
+			backoff *= 2
+			continue
+		}
+
+		if resp.ErrCode != 0 {
+			return nil, fmt.Errorf("errcode:%d errmsg:%s", resp.ErrCode, resp.ErrMsg)
+		}
+
+		return t, nil
+	}
+}
+
+// wait 等待到满足请求间隔
+func (exp *Exporter) wait(ctx context.Context) error {
+	if err := sleep(ctx, exp.interval-time.Since(exp.lastCall)); err != nil {
+		return err
+	}
+
+	exp.lastCall = time.Now()
+
+	return nil
+}
+
+// sleep 等待一段时间，ctx结束时提前返回
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// filename 指标族的导出文件
+func (exp *Exporter) filename(appID string, metric Metric) string {
+	return filepath.Join(exp.dir, fmt.Sprintf("%s_%s.%s", appID, metric, exp.format))
+}
+
+// write 先将文件截断到断点记录的长度，再追加指标数据，返回写入后的文件长度
+// offset小于0时直接追加到文件末尾
+func (exp *Exporter) write(filename string, offset int64, t *table) (int64, error) {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	if offset < 0 {
+		offset, err = file.Seek(0, io.SeekEnd)
+	} else if err = file.Truncate(offset); err == nil {
+		_, err = file.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if exp.format == FormatJSONL {
+		encoder := json.NewEncoder(file)
+		for _, row := range t.rows {
+			record := map[string]interface{}{}
+			for index, column := range t.header {
+				record[column] = row[index]
+			}
+			if err = encoder.Encode(record); err != nil {
+				return 0, err
+			}
+		}
+	} else {
+		writer := csv.NewWriter(file)
+		if offset == 0 {
+			writer.Write(t.header)
+		}
+		for _, row := range t.rows {
+			values := make([]string, len(row))
+			for index, value := range row {
+				values[index] = fmt.Sprint(value)
+			}
+			writer.Write(values)
+		}
+		writer.Flush()
+		if err = writer.Error(); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = file.Sync(); err != nil {
+		return 0, err
+	}
+
+	return file.Seek(0, io.SeekCurrent)
+}
+
+// fileSize 文件长度，文件不存在时为0
+func fileSize(filename string) (int64, error) {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return info.Size(), nil
+}
+
+// checkpointLocker 断点文件的锁
+func (exp *Exporter) checkpointLocker() *sync.Mutex {
+	filename, err := filepath.Abs(exp.checkpoint)
+	if err != nil {
+		filename = exp.checkpoint
+	}
+
+	defer checkpointLockersLocker.Unlock()
+	checkpointLockersLocker.Lock()
+
+	if checkpointLockers[filename] == nil {
+		checkpointLockers[filename] = &sync.Mutex{}
+	}
+
+	return checkpointLockers[filename]
+}
+
+// loadProgress 读取小程序各指标族的导出进度
+func (exp *Exporter) loadProgress(appID string) (map[Metric]*progress, error) {
+	if exp.checkpoint == "" {
+		return map[Metric]*progress{}, nil
+	}
+
+	locker := exp.checkpointLocker()
+	defer locker.Unlock()
+	locker.Lock()
+
+	cp, err := exp.loadCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	if cp.Apps[appID] == nil {
+		return map[Metric]*progress{}, nil
+	}
+
+	return cp.Apps[appID], nil
+}
+
+// loadCheckpoint 读取断点文件，调用方需持有断点文件的锁
+func (exp *Exporter) loadCheckpoint() (*checkpoint, error) {
+	cp := &checkpoint{Apps: map[string]map[Metric]*progress{}}
+
+	raw, err := ioutil.ReadFile(exp.checkpoint)
+	if os.IsNotExist(err) {
+		return cp, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(raw, cp); err != nil {
+		return nil, err
+	}
+	if cp.Apps == nil {
+		cp.Apps = map[string]map[Metric]*progress{}
+	}
+
+	return cp, nil
+}
+
+// saveCheckpoint 重新读取断点文件，只更新当前小程序的进度，避免覆盖共用断点文件的其他导出器
+// 先写临时文件再重命名，避免中断时断点文件损坏
+func (exp *Exporter) saveCheckpoint(appID string, done map[Metric]*progress) error {
+	if exp.checkpoint == "" {
+		return nil
+	}
+
+	locker := exp.checkpointLocker()
+	defer locker.Unlock()
+	locker.Lock()
+
+	cp, err := exp.loadCheckpoint()
+	if err != nil {
+		return err
+	}
+	cp.Apps[appID] = done
+
+	raw, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	tmp := exp.checkpoint + ".tmp"
+	if err = ioutil.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, exp.checkpoint)
+}
+
+// truncateDay 截断到所在时区的零点
+func truncateDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+func formatDay(t time.Time) string {
+	return t.Format("20060102")
+}
@@ -0,0 +1,103 @@
+package analytics
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amazing-gao/applet/api"
+	"github.com/amazing-gao/applet/applettest"
+)
+
+func newExporter(t *testing.T, appID, dir string) (*Exporter, *applettest.Server) {
+	srv := applettest.NewServer(appID, "secret")
+	srv.Handle("/datacube/getweanalysisappiddailysummarytrend", true, func(call *applettest.Call) (int, interface{}) {
+		return http.StatusOK, map[string]interface{}{
+			"list": []map[string]interface{}{{"ref_date": "20200101", "visit_total": 1}},
+		}
+	})
+
+	wechatAPI, err := api.NewWechatAPI(appID,
+		api.WithAppKey("secret"),
+		api.WithBaseURL(srv.URL),
+		api.WithLogger(log.New(ioutil.Discard, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exporter := NewExporter(wechatAPI, dir, FormatCSV).
+		SetMetrics(MetricDailySummary).
+		SetRateLimit(0).
+		SetCheckpoint(filepath.Join(dir, "checkpoint.json"))
+
+	return exporter, srv
+}
+
+func TestExportSharedCheckpoint(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "analytics")
+	defer os.RemoveAll(dir)
+
+	first, srv1 := newExporter(t, "wx1", dir)
+	defer srv1.Close()
+	second, srv2 := newExporter(t, "wx2", dir)
+	defer srv2.Close()
+
+	day := time.Now().In(beijing).AddDate(0, 0, -3)
+	if err := first.Export(context.Background(), day, day); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Export(context.Background(), day, day); err != nil {
+		t.Fatal(err)
+	}
+
+	cp, err := first.loadCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, appID := range []string{"wx1", "wx2"} {
+		if p := cp.Apps[appID][MetricDailySummary]; p == nil || p.Date != formatDay(day) {
+			t.Fatalf("checkpoint of %s got %+v", appID, cp.Apps[appID])
+		}
+	}
+}
+
+func TestExportKeepsExistingFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "analytics")
+	defer os.RemoveAll(dir)
+
+	exporter, srv := newExporter(t, "wx1", dir)
+	defer srv.Close()
+
+	filename := exporter.filename("wx1", MetricDailySummary)
+	existing := "ref_date,visit_total,share_pv,share_uv\n20190101,1,0,0\n"
+	ioutil.WriteFile(filename, []byte(existing), 0644)
+
+	day := time.Now().In(beijing).AddDate(0, 0, -3)
+	if err := exporter.Export(context.Background(), day, day); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _ := ioutil.ReadFile(filename)
+	if !strings.HasPrefix(string(raw), existing) || strings.Count(string(raw), "ref_date") != 1 {
+		t.Fatalf("existing rows were not kept:\n%s", raw)
+	}
+
+	// 断点之后写入的内容在重新导出时被截断
+	cp, _ := exporter.loadCheckpoint()
+	cp.Apps["wx1"][MetricDailySummary] = &progress{Offset: int64(len(existing))}
+	exporter.saveCheckpoint("wx1", cp.Apps["wx1"])
+	if err := exporter.Export(context.Background(), day, day); err != nil {
+		t.Fatal(err)
+	}
+
+	rerun, _ := ioutil.ReadFile(filename)
+	if string(rerun) != string(raw) {
+		t.Fatalf("rerun got:\n%s\nwant:\n%s", rerun, raw)
+	}
+}
@@ -0,0 +1,154 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/amazing-gao/applet/api"
+)
+
+const (
+	// MetricDailySummary 用户访问小程序数据概况
+	MetricDailySummary Metric = "daily_summary"
+	// MetricDailyRetain 用户访问小程序日留存
+	MetricDailyRetain Metric = "daily_retain"
+	// MetricDailyVisitTrend 用户访问小程序数据日趋势
+	MetricDailyVisitTrend Metric = "daily_visit_trend"
+	// MetricVisitDistribution 用户小程序访问分布数据
+	MetricVisitDistribution Metric = "visit_distribution"
+	// MetricVisitPage 访问页面数据
+	MetricVisitPage Metric = "visit_page"
+	// MetricUserPortrait 用户画像分布数据
+	MetricUserPortrait Metric = "user_portrait"
+)
+
+type (
+	// Metric 指标族，每个指标族导出到单独的文件
+	Metric string
+
+	// table 按天拉取的指标数据
+	table struct {
+		header []string
+		rows   [][]interface{}
+	}
+
+	// fetcher 拉取一天的指标数据
+	fetcher func(wechatAPI *api.WechatAPI, day time.Time) (*table, *api.WechatResp, []error)
+)
+
+// AllMetrics 全部支持按天导出的指标族
+var AllMetrics = []Metric{
+	MetricDailySummary,
+	MetricDailyRetain,
+	MetricDailyVisitTrend,
+	MetricVisitDistribution,
+	MetricVisitPage,
+	MetricUserPortrait,
+}
+
+var fetchers = map[Metric]fetcher{
+	MetricDailySummary:      fetchDailySummary,
+	MetricDailyRetain:       fetchDailyRetain,
+	MetricDailyVisitTrend:   fetchDailyVisitTrend,
+	MetricVisitDistribution: fetchVisitDistribution,
+	MetricVisitPage:         fetchVisitPage,
+	MetricUserPortrait:      fetchUserPortrait,
+}
+
+func fetchDailySummary(wechatAPI *api.WechatAPI, day time.Time) (*table, *api.WechatResp, []error) {
+	respData, resp, errs := wechatAPI.GetDailySummary(day, day)
+	t := &table{header: []string{"ref_date", "visit_total", "share_pv", "share_uv"}}
+	if respData != nil {
+		for _, item := range respData.List {
+			t.rows = append(t.rows, []interface{}{item.RefDate, item.VisitTotal, item.SharePV, item.ShareUV})
+		}
+	}
+
+	return t, resp, errs
+}
+
+func fetchDailyRetain(wechatAPI *api.WechatAPI, day time.Time) (*table, *api.WechatResp, []error) {
+	respData, resp, errs := wechatAPI.GetDailyRetain(day, day)
+	t := &table{header: []string{"ref_date", "type", "key", "value"}}
+	if respData != nil {
+		for _, item := range respData.VisitUVNew {
+			t.rows = append(t.rows, []interface{}{respData.RefDate, "visit_uv_new", item.Key, item.Value})
+		}
+		for _, item := range respData.VisitUV {
+			t.rows = append(t.rows, []interface{}{respData.RefDate, "visit_uv", item.Key, item.Value})
+		}
+	}
+
+	return t, resp, errs
+}
+
+func fetchDailyVisitTrend(wechatAPI *api.WechatAPI, day time.Time) (*table, *api.WechatResp, []error) {
+	respData, resp, errs := wechatAPI.GetDailyVisitTrend(day, day)
+	t := &table{header: []string{"ref_date", "session_cnt", "visit_pv", "visit_uv", "visit_uv_new", "stay_time_uv", "stay_time_session", "visit_depth"}}
+	if respData != nil {
+		for _, item := range respData.List {
+			t.rows = append(t.rows, []interface{}{item.RefDate, item.SessionCnt, item.VisitPV, item.VisitUV, item.VisitUVNew, item.StayTimeUV, item.StayTimeSession, item.VisitDepth})
+		}
+	}
+
+	return t, resp, errs
+}
+
+func fetchVisitDistribution(wechatAPI *api.WechatAPI, day time.Time) (*table, *api.WechatResp, []error) {
+	respData, resp, errs := wechatAPI.GetVisitDistribution(day, day)
+	t := &table{header: []string{"ref_date", "index", "key", "value", "access_source_visit_uv"}}
+	if respData != nil {
+		for _, list := range respData.List {
+			for _, item := range list.ItemList {
+				t.rows = append(t.rows, []interface{}{respData.RefDate, list.Index, item.Key, item.Value, item.AccessSourceVisitUV})
+			}
+		}
+	}
+
+	return t, resp, errs
+}
+
+func fetchVisitPage(wechatAPI *api.WechatAPI, day time.Time) (*table, *api.WechatResp, []error) {
+	respData, resp, errs := wechatAPI.GetVisitPage(day, day)
+	t := &table{header: []string{"ref_date", "page_path", "page_visit_pv", "page_visit_uv", "page_staytime_pv", "entrypage_pv", "exitpage_pv", "page_share_pv", "page_share_uv"}}
+	if respData != nil {
+		for _, item := range respData.List {
+			t.rows = append(t.rows, []interface{}{respData.RefDate, item.PagePath, item.PageVisitPV, item.PageVisitUV, item.PageStaytimePV, item.EntrypagePV, item.ExitpagePV, item.PageSharePV, item.PageShareUV})
+		}
+	}
+
+	return t, resp, errs
+}
+
+func fetchUserPortrait(wechatAPI *api.WechatAPI, day time.Time) (*table, *api.WechatResp, []error) {
+	respData, resp, errs := wechatAPI.GetUserPortrait(day, day)
+	t := &table{header: []string{"ref_date", "type", "dimension", "id", "name", "value"}}
+	if respData != nil {
+		portraits := []struct {
+			name     string
+			portrait api.UserPortrait
+		}{
+			{"visit_uv_new", respData.VisitUVNew},
+			{"visit_uv", respData.VisitUV},
+		}
+		for _, p := range portraits {
+			dimensions := []struct {
+				name   string
+				values []api.UserPortraitValue
+			}{
+				{"province", p.portrait.Province},
+				{"city", p.portrait.City},
+				{"genders", p.portrait.Genders},
+				{"platforms", p.portrait.Platforms},
+				{"devices", p.portrait.Devices},
+				{"ages", p.portrait.Ages},
+			}
+			for _, dimension := range dimensions {
+				for _, value := range dimension.values {
+					t.rows = append(t.rows, []interface{}{respData.RefDate, p.name, dimension.name, value.ID, value.Name, value.Value})
+				}
+			}
+		}
+	}
+
+	return t, resp, errs
+}
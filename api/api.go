@@ -2,10 +2,16 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"net/http"
 	"net/url"
+	"os"
 	"path"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/parnurzeal/gorequest"
 )
@@ -21,7 +27,13 @@ type (
 		apiTokenStore WechatTokenStore
 		before        Before
 		after         After
+		httpClient    *http.Client
+		logger        Logger
+		retryCount    int
+		retryInterval time.Duration
 		locker        *sync.Mutex
+		renewer       renewer          // 自定义的token生成方式，默认client_credential
+		component     *WechatComponent // 第三方平台，代授权方调用接口时使用
 	}
 
 	// WechatResp 微信接口响应
@@ -36,56 +48,164 @@ type (
 	// After request
 	After func(*gorequest.SuperAgent, []error, string, *gorequest.Response)
 
+	renewer func() (*WechatRespToken, *WechatResp, []error)
+
 	option struct {
 		method    string
 		url       string
 		withToken bool
 		query     interface{}
 		body      interface{}
+		retryable bool // 非GET请求可安全重放时设置，网络错误及5xx响应时按WithRetry的配置重试
 	}
 )
 
 // NewWechatAPI 生成一个api
-func NewWechatAPI(appID, appKey string, tokenStore WechatTokenStore) *WechatAPI {
-	return &WechatAPI{
+func NewWechatAPI(appID string, opts ...Option) (*WechatAPI, error) {
+	if appID == "" {
+		return nil, errors.New("appID is empty")
+	}
+
+	api := &WechatAPI{
 		apiScheme:     "https",
 		apiDomain:     "api.weixin.qq.com",
 		apiBasePath:   "/",
 		appID:         appID,
-		appKey:        appKey,
-		apiTokenStore: tokenStore,
+		apiTokenStore: NewMemoryTokenStore(),
+		logger:        log.New(os.Stderr, "", log.LstdFlags),
 		locker:        &sync.Mutex{},
 	}
+
+	for _, opt := range opts {
+		if err := opt(api); err != nil {
+			return nil, err
+		}
+	}
+
+	return api, nil
+}
+
+// AppID 小程序appid
+func (api *WechatAPI) AppID() string {
+	return api.appID
 }
 
 // SetBefore 设置请求前hook
+//
+// Deprecated: 并发使用时不安全，请使用WithBefore
 func (api *WechatAPI) SetBefore(be Before) {
 	api.before = be
 }
 
 // SetAftre 设置请求后hook
+//
+// Deprecated: 并发使用时不安全，请使用WithAfter
 func (api *WechatAPI) SetAftre(af After) {
 	api.after = af
 }
 
 // SetScheme 设置http/https schema，覆盖默认的https
+//
+// Deprecated: 并发使用时不安全，请使用WithBaseURL
 func (api *WechatAPI) SetScheme(scheme string) {
 	api.apiScheme = scheme
 }
 
 // SetDomain 设置domain，覆盖默认的api.weixin.qq.com
+//
+// Deprecated: 并发使用时不安全，请使用WithBaseURL
 func (api *WechatAPI) SetDomain(domain string) {
 	api.apiDomain = domain
 }
 
 // SetBasePath 设置请求的BasePath，覆盖默认的/
+//
+// Deprecated: 并发使用时不安全，请使用WithBaseURL
 func (api *WechatAPI) SetBasePath(basePath string) {
 	api.apiBasePath = basePath
 }
 
 // Request 请求
 func (api *WechatAPI) Request(opt *option, respData ...interface{}) (*WechatResp, []error) {
+	body, errs := api.do(opt)
+	if len(errs) != 0 {
+		return nil, errs
+	}
+
+	wechatResp := &WechatResp{}
+	if err := json.Unmarshal([]byte(body), wechatResp); err != nil {
+		return nil, []error{err}
+	}
+
+	// 业务错误，直接返回，成功时部分接口返回errmsg:ok
+	if wechatResp.ErrCode != 0 {
+		return wechatResp, nil
+	}
+
+	// 业务成功，获取返回的数据
+	if len(respData) != 0 {
+		if err := json.Unmarshal([]byte(body), respData[0]); err != nil {
+			return wechatResp, []error{err}
+		}
+	}
+
+	return wechatResp, nil
+}
+
+// RequestRaw 请求返回二进制内容的接口，例如小程序码
+// 接口返回json时视为业务错误
+func (api *WechatAPI) RequestRaw(opt *option) ([]byte, *WechatResp, []error) {
+	body, errs := api.do(opt)
+	if len(errs) != 0 {
+		return nil, nil, errs
+	}
+
+	if strings.HasPrefix(body, "{") {
+		wechatResp := &WechatResp{}
+		if err := json.Unmarshal([]byte(body), wechatResp); err != nil {
+			return nil, nil, []error{err}
+		}
+		return nil, wechatResp, nil
+	}
+
+	return []byte(body), &WechatResp{}, nil
+}
+
+// do 发送请求，GET请求及retryable的请求在网络错误及5xx响应时按WithRetry的配置重试
+// 其他请求可能已被微信处理，不重试以免重复执行
+func (api *WechatAPI) do(opt *option) (string, []error) {
+	retryCount := 0
+	if opt.method == "GET" || opt.retryable {
+		retryCount = api.retryCount
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, body, errs := api.request(opt)
+		if len(errs) == 0 && resp.StatusCode < http.StatusInternalServerError {
+			return body, nil
+		}
+
+		if len(errs) == 0 {
+			errs = []error{fmt.Errorf("wechat api %s response status: %s", opt.url, resp.Status)}
+		}
+		if attempt >= retryCount {
+			return body, errs
+		}
+
+		api.logger.Printf("Applet.Request.Retry url:%s attempt:%d errs:%v", opt.url, attempt+1, errs)
+		time.Sleep(api.retryInterval)
+	}
+}
+
+func (api *WechatAPI) request(opt *option) (gorequest.Response, string, []error) {
 	req := gorequest.New()
+	if api.httpClient != nil {
+		client := *api.httpClient
+		req.Client = &client
+		if transport, ok := client.Transport.(*http.Transport); ok {
+			req.Transport = transport
+		}
+	}
 
 	u := &url.URL{
 		Scheme: api.apiScheme,
@@ -115,26 +235,5 @@ func (api *WechatAPI) Request(opt *option, respData ...interface{}) (*WechatResp
 		api.after(req, errs, body, &resp)
 	}
 
-	if len(errs) != 0 {
-		return nil, errs
-	}
-
-	wechatResp := &WechatResp{}
-	if err := json.Unmarshal([]byte(body), wechatResp); err != nil {
-		return nil, []error{err}
-	}
-
-	// 业务错误，直接返回
-	if wechatResp.ErrCode != 0 || wechatResp.ErrMsg != "" {
-		return wechatResp, nil
-	}
-
-	// 业务成功，获取返回的数据
-	if len(respData) != 0 {
-		if err := json.Unmarshal([]byte(body), respData[0]); err != nil {
-			return wechatResp, []error{err}
-		}
-	}
-
-	return wechatResp, nil
+	return resp, body, errs
 }
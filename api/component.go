@@ -0,0 +1,217 @@
+package api
+
+import (
+	"fmt"
+	"sync"
+)
+
+type (
+	// ComponentTicketStore 第三方平台component_verify_ticket存储器
+	ComponentTicketStore interface {
+		Get() (ticket string)
+		Set(ticket string)
+	}
+
+	// AuthorizerRefreshTokenStore 授权方authorizer_refresh_token存储器
+	AuthorizerRefreshTokenStore interface {
+		Get(authorizerAppID string) (refreshToken string)
+		Set(authorizerAppID, refreshToken string)
+	}
+
+	// WechatComponent 第三方平台API，token为component_access_token
+	WechatComponent struct {
+		api               *WechatAPI
+		ticketStore       ComponentTicketStore
+		refreshTokenStore AuthorizerRefreshTokenStore
+	}
+
+	// RespComponentToken 第三方平台component_access_token响应结果
+	RespComponentToken struct {
+		ComponentAccessToken string `json:"component_access_token"`
+		ExpiresIn            int    `json:"expires_in"`
+	}
+
+	// RespPreAuthCode 预授权码响应结果
+	RespPreAuthCode struct {
+		PreAuthCode string `json:"pre_auth_code"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+
+	// RespQueryAuth 使用授权码获取授权信息响应结果
+	RespQueryAuth struct {
+		AuthorizationInfo AuthorizationInfo `json:"authorization_info"`
+	}
+
+	// AuthorizationInfo 授权信息
+	AuthorizationInfo struct {
+		AuthorizerAppID        string `json:"authorizer_appid"`
+		AuthorizerAccessToken  string `json:"authorizer_access_token"`
+		ExpiresIn              int    `json:"expires_in"`
+		AuthorizerRefreshToken string `json:"authorizer_refresh_token"`
+		FuncInfo               []struct {
+			FuncscopeCategory struct {
+				ID int `json:"id"`
+			} `json:"funcscope_category"`
+		} `json:"func_info"`
+	}
+
+	// RespAuthorizerToken 刷新授权方接口调用令牌响应结果
+	RespAuthorizerToken struct {
+		AuthorizerAccessToken  string `json:"authorizer_access_token"`
+		ExpiresIn              int    `json:"expires_in"`
+		AuthorizerRefreshToken string `json:"authorizer_refresh_token"`
+	}
+)
+
+// NewWechatComponent 生成一个第三方平台api，opts用于配置component_access_token存储器、http客户端等
+func NewWechatComponent(appID, appSecret string, ticketStore ComponentTicketStore, refreshTokenStore AuthorizerRefreshTokenStore, opts ...Option) (*WechatComponent, error) {
+	api, err := NewWechatAPI(appID, append([]Option{WithAppKey(appSecret)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	comp := &WechatComponent{
+		api:               api,
+		ticketStore:       ticketStore,
+		refreshTokenStore: refreshTokenStore,
+	}
+	comp.api.renewer = comp.renewComponentToken
+
+	return comp, nil
+}
+
+// API 第三方平台自身的api
+func (comp *WechatComponent) API() *WechatAPI {
+	return comp.api
+}
+
+// SetTicket 保存微信推送的component_verify_ticket
+func (comp *WechatComponent) SetTicket(ticket string) {
+	comp.ticketStore.Set(ticket)
+}
+
+// GetToken 获取component_access_token
+func (comp *WechatComponent) GetToken() string {
+	return comp.api.GetToken()
+}
+
+// RenewToken 使用component_verify_ticket重新生成component_access_token
+func (comp *WechatComponent) RenewToken() (*WechatResp, []error) {
+	return comp.api.RenewToken()
+}
+
+// CreatePreAuthCode 获取预授权码
+func (comp *WechatComponent) CreatePreAuthCode() (*RespPreAuthCode, *WechatResp, []error) {
+	respData := &RespPreAuthCode{}
+	resp, errs := comp.api.Request(&option{
+		method: "POST",
+		url:    "/cgi-bin/component/api_create_preauthcode",
+		query:  comp.tokenQuery(),
+		body: map[string]string{
+			"component_appid": comp.api.appID,
+		},
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// QueryAuth 使用授权码获取授权信息，并保存授权方的authorizer_refresh_token
+func (comp *WechatComponent) QueryAuth(authorizationCode string) (*RespQueryAuth, *WechatResp, []error) {
+	respData := &RespQueryAuth{}
+	resp, errs := comp.api.Request(&option{
+		method: "POST",
+		url:    "/cgi-bin/component/api_query_auth",
+		query:  comp.tokenQuery(),
+		body: map[string]string{
+			"component_appid":    comp.api.appID,
+			"authorization_code": authorizationCode,
+		},
+	}, respData)
+
+	if len(errs) == 0 && resp != nil && resp.ErrCode == 0 {
+		info := respData.AuthorizationInfo
+		comp.refreshTokenStore.Set(info.AuthorizerAppID, info.AuthorizerRefreshToken)
+	}
+
+	return respData, resp, errs
+}
+
+// Code2Session 代授权的小程序将oauth code转换为unionId,openId,sessionKey
+func (comp *WechatComponent) Code2Session(authorizerAppID, code string) (*RespCode2Session, *WechatResp, []error) {
+	respData := &RespCode2Session{}
+	resp, errs := comp.api.Request(&option{
+		method: "GET",
+		url:    "/sns/component/jscode2session",
+		query: map[string]string{
+			"appid":                  authorizerAppID,
+			"js_code":                code,
+			"grant_type":             "authorization_code",
+			"component_appid":        comp.api.appID,
+			"component_access_token": comp.GetToken(),
+		},
+	}, respData)
+
+	if len(errs) != 0 || resp.ErrCode != 0 {
+		return nil, resp, errs
+	}
+
+	return respData, resp, errs
+}
+
+// NewAuthorizerAPI 生成一个代授权小程序调用的api，沿用第三方平台的接口地址、http客户端及hook
+// token为authorizer_access_token，RenewToken时使用authorizer_refresh_token刷新
+func (comp *WechatComponent) NewAuthorizerAPI(authorizerAppID string, tokenStore WechatTokenStore) *WechatAPI {
+	api := *comp.api
+	api.appID = authorizerAppID
+	api.appKey = ""
+	api.apiTokenStore = tokenStore
+	api.locker = &sync.Mutex{}
+	api.component = comp
+	api.renewer = func() (*WechatRespToken, *WechatResp, []error) {
+		return comp.renewAuthorizerToken(authorizerAppID)
+	}
+
+	return &api
+}
+
+// renewComponentToken 使用component_verify_ticket生成component_access_token
+func (comp *WechatComponent) renewComponentToken() (*WechatRespToken, *WechatResp, []error) {
+	respData := &RespComponentToken{}
+	resp, errs := comp.api.Request(&option{
+		method: "POST",
+		url:    "/cgi-bin/component/api_component_token",
+		body: map[string]string{
+			"component_appid":         comp.api.appID,
+			"component_appsecret":     comp.api.appKey,
+			"component_verify_ticket": comp.ticketStore.Get(),
+		},
+	}, respData)
+
+	return &WechatRespToken{Token: respData.ComponentAccessToken, ExpiresIn: respData.ExpiresIn}, resp, errs
+}
+
+// renewAuthorizerToken 使用authorizer_refresh_token刷新authorizer_access_token
+func (comp *WechatComponent) renewAuthorizerToken(authorizerAppID string) (*WechatRespToken, *WechatResp, []error) {
+	respData := &RespAuthorizerToken{}
+	resp, errs := comp.api.Request(&option{
+		method: "POST",
+		url:    "/cgi-bin/component/api_authorizer_token",
+		query:  comp.tokenQuery(),
+		body: map[string]string{
+			"component_appid":          comp.api.appID,
+			"authorizer_appid":         authorizerAppID,
+			"authorizer_refresh_token": comp.refreshTokenStore.Get(authorizerAppID),
+		},
+	}, respData)
+
+	// 刷新令牌可能会更新，需保存最新的
+	if len(errs) == 0 && resp != nil && resp.ErrCode == 0 && respData.AuthorizerRefreshToken != "" {
+		comp.refreshTokenStore.Set(authorizerAppID, respData.AuthorizerRefreshToken)
+	}
+
+	return &WechatRespToken{Token: respData.AuthorizerAccessToken, ExpiresIn: respData.ExpiresIn}, resp, errs
+}
+
+func (comp *WechatComponent) tokenQuery() string {
+	return fmt.Sprintf("component_access_token=%s", comp.GetToken())
+}
@@ -1,5 +1,12 @@
 package api
 
+const (
+	// Typing 对用户下发"正在输入"状态
+	Typing = "Typing"
+	// CancelTyping 取消对用户的"正在输入"状态
+	CancelTyping = "CancelTyping"
+)
+
 type (
 	// CustomerMsg 客服消息主体
 	CustomerMsg struct {
@@ -11,6 +18,12 @@ type (
 		Applet  *CustomerMsgApplet `json:"miniprogrampage,omitempty"`
 	}
 
+	// CustomerTyping 客服输入状态
+	CustomerTyping struct {
+		OpenID  string `json:"touser"`
+		Command string `json:"command"` // Typing,CancelTyping
+	}
+
 	// 文本
 	CustomerMsgText struct {
 		Content string `json:"content"`
@@ -86,3 +99,16 @@ func (api *WechatAPI) SendApplet(openid string, applet *CustomerMsgApplet) (*Wec
 		Applet:  applet,
 	})
 }
+
+// SetTyping 下发客服当前输入状态给用户
+func (api *WechatAPI) SetTyping(openid, command string) (*WechatResp, []error) {
+	return api.Request(&option{
+		method:    "POST",
+		url:       "/cgi-bin/message/custom/typing",
+		withToken: true,
+		body: &CustomerTyping{
+			OpenID:  openid,
+			Command: command,
+		},
+	})
+}
@@ -0,0 +1,362 @@
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// datacubeDateLayout 数据分析接口的日期格式
+	datacubeDateLayout = "20060102"
+)
+
+var (
+	// datacubeLocation 数据分析接口按北京时间统计
+	datacubeLocation = time.FixedZone("CST", 8*60*60)
+)
+
+type (
+	// DatacubeRange 数据分析接口的查询时间范围
+	DatacubeRange struct {
+		BeginDate string `json:"begin_date"`
+		EndDate   string `json:"end_date"`
+	}
+
+	// RespDailySummary 用户访问小程序数据概况
+	RespDailySummary struct {
+		List []DailySummary `json:"list"`
+	}
+
+	// DailySummary 一天的数据概况
+	DailySummary struct {
+		RefDate    string `json:"ref_date"`    // 日期，格式为 yyyymmdd
+		VisitTotal int    `json:"visit_total"` // 累计用户数
+		SharePV    int    `json:"share_pv"`    // 转发次数
+		ShareUV    int    `json:"share_uv"`    // 转发人数
+	}
+
+	// RespRetain 用户访问小程序留存
+	RespRetain struct {
+		RefDate    string          `json:"ref_date"`     // 时间，如 20170306-20170312
+		VisitUVNew []DatacubeValue `json:"visit_uv_new"` // 新增用户留存，key 0表示当天，1表示1天后
+		VisitUV    []DatacubeValue `json:"visit_uv"`     // 活跃用户留存
+	}
+
+	// DatacubeValue 数据分析的键值
+	DatacubeValue struct {
+		Key   int `json:"key"`
+		Value int `json:"value"`
+	}
+
+	// RespVisitTrend 用户访问小程序数据趋势
+	RespVisitTrend struct {
+		List []VisitTrend `json:"list"`
+	}
+
+	// VisitTrend 一个周期的访问趋势
+	VisitTrend struct {
+		RefDate         string  `json:"ref_date"`          // 时间
+		SessionCnt      int     `json:"session_cnt"`       // 打开次数
+		VisitPV         int     `json:"visit_pv"`          // 访问次数
+		VisitUV         int     `json:"visit_uv"`          // 访问人数
+		VisitUVNew      int     `json:"visit_uv_new"`      // 新用户数
+		StayTimeUV      float64 `json:"stay_time_uv"`      // 人均停留时长 (浮点型，单位：秒)
+		StayTimeSession float64 `json:"stay_time_session"` // 次均停留时长 (浮点型，单位：秒)
+		VisitDepth      float64 `json:"visit_depth"`       // 平均访问深度 (浮点型)
+	}
+
+	// RespVisitDistribution 用户小程序访问分布数据
+	RespVisitDistribution struct {
+		RefDate string              `json:"ref_date"`
+		List    []VisitDistribution `json:"list"`
+	}
+
+	// VisitDistribution 一项指标的访问分布
+	VisitDistribution struct {
+		Index    string                  `json:"index"` // access_source_session_cnt access_staytime_info access_depth_info
+		ItemList []VisitDistributionItem `json:"item_list"`
+	}
+
+	// VisitDistributionItem 访问分布的区间及数值
+	VisitDistributionItem struct {
+		Key                 int `json:"key"`
+		Value               int `json:"value"`
+		AccessSourceVisitUV int `json:"access_source_visit_uv"`
+	}
+
+	// RespVisitPage 访问页面数据
+	RespVisitPage struct {
+		RefDate string      `json:"ref_date"`
+		List    []VisitPage `json:"list"`
+	}
+
+	// VisitPage 一个页面的访问数据
+	VisitPage struct {
+		PagePath       string  `json:"page_path"`        // 页面路径
+		PageVisitPV    int     `json:"page_visit_pv"`    // 访问次数
+		PageVisitUV    int     `json:"page_visit_uv"`    // 访问人数
+		PageStaytimePV float64 `json:"page_staytime_pv"` // 次均停留时长
+		EntrypagePV    int     `json:"entrypage_pv"`     // 进入页次数
+		ExitpagePV     int     `json:"exitpage_pv"`      // 退出页次数
+		PageSharePV    int     `json:"page_share_pv"`    // 转发次数
+		PageShareUV    int     `json:"page_share_uv"`    // 转发人数
+	}
+
+	// RespUserPortrait 用户画像分布数据
+	RespUserPortrait struct {
+		RefDate    string       `json:"ref_date"`
+		VisitUVNew UserPortrait `json:"visit_uv_new"` // 新用户画像
+		VisitUV    UserPortrait `json:"visit_uv"`     // 活跃用户画像
+	}
+
+	// UserPortrait 用户画像
+	UserPortrait struct {
+		Index     int                 `json:"index"`
+		Province  []UserPortraitValue `json:"province"`  // 省份
+		City      []UserPortraitValue `json:"city"`      // 城市
+		Genders   []UserPortraitValue `json:"genders"`   // 性别
+		Platforms []UserPortraitValue `json:"platforms"` // 终端类型
+		Devices   []UserPortraitValue `json:"devices"`   // 机型
+		Ages      []UserPortraitValue `json:"ages"`      // 年龄
+	}
+
+	// UserPortraitValue 用户画像的属性值
+	UserPortraitValue struct {
+		ID    int    `json:"id"`
+		Name  string `json:"name"`
+		Value int    `json:"value"`
+	}
+
+	// PerformanceQuery 小程序性能数据查询参数
+	PerformanceQuery struct {
+		Time struct {
+			BeginTimestamp int64 `json:"begin_timestamp"`
+			EndTimestamp   int64 `json:"end_timestamp"`
+		} `json:"time"`
+		Module string             `json:"module"` // 10016 打开率 10017 启动各阶段耗时 10021 页面切换耗时 ...
+		Params []PerformanceParam `json:"params"`
+	}
+
+	// PerformanceParam 小程序性能数据查询条件
+	PerformanceParam struct {
+		Field string `json:"field"` // networktype device_level device
+		Value string `json:"value"`
+	}
+
+	// RespPerformance 小程序性能数据
+	RespPerformance struct {
+		Data struct {
+			Body struct {
+				Tables []PerformanceTable `json:"tables"`
+				Count  int                `json:"count"`
+			} `json:"body"`
+		} `json:"data"`
+	}
+
+	// PerformanceTable 性能数据表
+	PerformanceTable struct {
+		ID    string            `json:"id"`
+		Lines []PerformanceLine `json:"lines"`
+		Zh    string            `json:"zh"`
+	}
+
+	// PerformanceLine 性能数据表的一行
+	PerformanceLine struct {
+		Fields []PerformanceField `json:"fields"`
+	}
+
+	// PerformanceField 性能数据的取值
+	PerformanceField struct {
+		RefDate string `json:"refdate"`
+		Value   string `json:"value"`
+	}
+)
+
+// GetDailySummary 获取用户访问小程序数据概况，只能查询一天
+func (api *WechatAPI) GetDailySummary(begin, end time.Time) (*RespDailySummary, *WechatResp, []error) {
+	respData := &RespDailySummary{}
+	resp, errs := api.datacube("/datacube/getweanalysisappiddailysummarytrend", checkDaily, begin, end, respData)
+
+	return respData, resp, errs
+}
+
+// GetDailyRetain 获取用户访问小程序日留存，只能查询一天
+func (api *WechatAPI) GetDailyRetain(begin, end time.Time) (*RespRetain, *WechatResp, []error) {
+	respData := &RespRetain{}
+	resp, errs := api.datacube("/datacube/getweanalysisappiddailyretaininfo", checkDaily, begin, end, respData)
+
+	return respData, resp, errs
+}
+
+// GetWeeklyRetain 获取用户访问小程序周留存，开始日期为周一，结束日期为周日
+func (api *WechatAPI) GetWeeklyRetain(begin, end time.Time) (*RespRetain, *WechatResp, []error) {
+	respData := &RespRetain{}
+	resp, errs := api.datacube("/datacube/getweanalysisappidweeklyretaininfo", checkWeekly, begin, end, respData)
+
+	return respData, resp, errs
+}
+
+// GetMonthlyRetain 获取用户访问小程序月留存，开始日期为自然月第一天，结束日期为自然月最后一天
+func (api *WechatAPI) GetMonthlyRetain(begin, end time.Time) (*RespRetain, *WechatResp, []error) {
+	respData := &RespRetain{}
+	resp, errs := api.datacube("/datacube/getweanalysisappidmonthlyretaininfo", checkMonthly, begin, end, respData)
+
+	return respData, resp, errs
+}
+
+// GetDailyVisitTrend 获取用户访问小程序数据日趋势，只能查询一天
+func (api *WechatAPI) GetDailyVisitTrend(begin, end time.Time) (*RespVisitTrend, *WechatResp, []error) {
+	respData := &RespVisitTrend{}
+	resp, errs := api.datacube("/datacube/getweanalysisappiddailyvisittrend", checkDaily, begin, end, respData)
+
+	return respData, resp, errs
+}
+
+// GetWeeklyVisitTrend 获取用户访问小程序数据周趋势，开始日期为周一，结束日期为周日
+func (api *WechatAPI) GetWeeklyVisitTrend(begin, end time.Time) (*RespVisitTrend, *WechatResp, []error) {
+	respData := &RespVisitTrend{}
+	resp, errs := api.datacube("/datacube/getweanalysisappidweeklyvisittrend", checkWeekly, begin, end, respData)
+
+	return respData, resp, errs
+}
+
+// GetMonthlyVisitTrend 获取用户访问小程序数据月趋势，开始日期为自然月第一天，结束日期为自然月最后一天
+func (api *WechatAPI) GetMonthlyVisitTrend(begin, end time.Time) (*RespVisitTrend, *WechatResp, []error) {
+	respData := &RespVisitTrend{}
+	resp, errs := api.datacube("/datacube/getweanalysisappidmonthlyvisittrend", checkMonthly, begin, end, respData)
+
+	return respData, resp, errs
+}
+
+// GetVisitDistribution 获取用户小程序访问分布数据，只能查询一天
+func (api *WechatAPI) GetVisitDistribution(begin, end time.Time) (*RespVisitDistribution, *WechatResp, []error) {
+	respData := &RespVisitDistribution{}
+	resp, errs := api.datacube("/datacube/getweanalysisappidvisitdistribution", checkDaily, begin, end, respData)
+
+	return respData, resp, errs
+}
+
+// GetVisitPage 获取访问页面数据，只能查询一天
+func (api *WechatAPI) GetVisitPage(begin, end time.Time) (*RespVisitPage, *WechatResp, []error) {
+	respData := &RespVisitPage{}
+	resp, errs := api.datacube("/datacube/getweanalysisappidvisitpage", checkDaily, begin, end, respData)
+
+	return respData, resp, errs
+}
+
+// GetUserPortrait 获取小程序新增或活跃用户的画像分布数据，可查询最近1天、7天或30天
+func (api *WechatAPI) GetUserPortrait(begin, end time.Time) (*RespUserPortrait, *WechatResp, []error) {
+	respData := &RespUserPortrait{}
+	resp, errs := api.datacube("/datacube/getweanalysisappiduserportrait", checkPortrait, begin, end, respData)
+
+	return respData, resp, errs
+}
+
+// GetPerformanceData 获取小程序启动性能，运行性能等数据
+func (api *WechatAPI) GetPerformanceData(module string, begin, end time.Time, params ...PerformanceParam) (*RespPerformance, *WechatResp, []error) {
+	if !begin.Before(end) {
+		return nil, nil, []error{fmt.Errorf("invalid performance range: %s - %s", begin, end)}
+	}
+
+	query := &PerformanceQuery{Module: module, Params: params}
+	query.Time.BeginTimestamp = begin.Unix()
+	query.Time.EndTimestamp = end.Unix()
+	if query.Params == nil {
+		query.Params = []PerformanceParam{}
+	}
+
+	respData := &RespPerformance{}
+	resp, errs := api.Request(&option{
+		method:    "POST",
+		url:       "/wxa/business/performance/boot",
+		withToken: true,
+		retryable: true,
+		body:      query,
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// datacube 校验时间范围后请求数据分析接口
+// begin、end取所在时区的日期，按北京时间的日期查询
+func (api *WechatAPI) datacube(url string, check func(begin, end time.Time) error, begin, end time.Time, respData interface{}) (*WechatResp, []error) {
+	begin, end = truncateDay(begin), truncateDay(end)
+	if err := check(begin, end); err != nil {
+		return nil, []error{err}
+	}
+
+	return api.Request(&option{
+		method:    "POST",
+		url:       url,
+		withToken: true,
+		retryable: true,
+		body: &DatacubeRange{
+			BeginDate: begin.Format(datacubeDateLayout),
+			EndDate:   end.Format(datacubeDateLayout),
+		},
+	}, respData)
+}
+
+// checkDaily 开始日期与结束日期相同，且不晚于昨天
+func checkDaily(begin, end time.Time) error {
+	if !begin.Equal(end) {
+		return fmt.Errorf("daily range must be one day: %s - %s", formatDay(begin), formatDay(end))
+	}
+
+	return checkBeforeToday(end)
+}
+
+// checkWeekly 开始日期为周一，结束日期为同一周的周日，且不晚于昨天
+func checkWeekly(begin, end time.Time) error {
+	if begin.Weekday() != time.Monday || !end.Equal(begin.AddDate(0, 0, 6)) {
+		return fmt.Errorf("weekly range must be monday to sunday: %s - %s", formatDay(begin), formatDay(end))
+	}
+
+	return checkBeforeToday(end)
+}
+
+// checkMonthly 开始日期为自然月第一天，结束日期为同月最后一天，且不晚于昨天
+func checkMonthly(begin, end time.Time) error {
+	if begin.Day() != 1 || !end.Equal(begin.AddDate(0, 1, -1)) {
+		return fmt.Errorf("monthly range must be a natural month: %s - %s", formatDay(begin), formatDay(end))
+	}
+
+	return checkBeforeToday(end)
+}
+
+// checkPortrait 结束日期为昨天，时间跨度为1天、7天或30天
+func checkPortrait(begin, end time.Time) error {
+	days := int(end.Sub(begin).Hours()/24) + 1
+	if days != 1 && days != 7 && days != 30 {
+		return fmt.Errorf("portrait range must be 1, 7 or 30 days: %s - %s", formatDay(begin), formatDay(end))
+	}
+
+	if yesterday := today().AddDate(0, 0, -1); !end.Equal(yesterday) {
+		return fmt.Errorf("portrait end date must be yesterday %s: %s", formatDay(yesterday), formatDay(end))
+	}
+
+	return nil
+}
+
+func checkBeforeToday(day time.Time) error {
+	if !day.Before(today()) {
+		return fmt.Errorf("date must be before today: %s", formatDay(day))
+	}
+
+	return nil
+}
+
+// today 北京时间的今天零点
+func today() time.Time {
+	return truncateDay(time.Now().In(datacubeLocation))
+}
+
+// truncateDay 取所在时区的日期，返回该日期北京时间的零点
+func truncateDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, datacubeLocation)
+}
+
+func formatDay(t time.Time) string {
+	return t.Format(datacubeDateLayout)
+}
@@ -0,0 +1,284 @@
+package api
+
+const (
+	// ExpressSourceWxa 小程序订单
+	ExpressSourceWxa = 0
+	// ExpressSourceApp App或H5订单
+	ExpressSourceApp = 2
+)
+
+const (
+	// ExpressPathCollected 揽件成功
+	ExpressPathCollected = 100001
+	// ExpressPathCollectFailed 揽件失败
+	ExpressPathCollectFailed = 100002
+	// ExpressPathAssigned 分配业务员
+	ExpressPathAssigned = 100003
+	// ExpressPathUpdated 更新业务员
+	ExpressPathUpdated = 100004
+	// ExpressPathTransporting 运输中
+	ExpressPathTransporting = 200001
+	// ExpressPathDelivering 派件中
+	ExpressPathDelivering = 300002
+	// ExpressPathSigned 妥投
+	ExpressPathSigned = 300003
+	// ExpressPathSignFailed 派件失败
+	ExpressPathSignFailed = 300004
+	// ExpressPathCancelled 订单取消
+	ExpressPathCancelled = 400001
+	// ExpressPathExceptional 订单异常
+	ExpressPathExceptional = 400002
+)
+
+type (
+	// ExpressDelivery 快递公司信息
+	ExpressDelivery struct {
+		DeliveryID   string               `json:"delivery_id"`   // 快递公司ID
+		DeliveryName string               `json:"delivery_name"` // 快递公司名称
+		CanUseCash   int                  `json:"can_use_cash"`  // 是否支持散单，1表示支持
+		CanGetQuota  int                  `json:"can_get_quota"` // 是否支持查询面单余额，1表示支持
+		CashBizID    string               `json:"cash_biz_id"`   // 散单对应的bizid
+		ServiceType  []ExpressServiceType `json:"service_type"`
+	}
+
+	// ExpressServiceType 快递公司支持的服务类型
+	ExpressServiceType struct {
+		ServiceType int    `json:"service_type"`
+		ServiceName string `json:"service_name"`
+	}
+
+	// RespExpressDeliveryList 快递公司列表
+	RespExpressDeliveryList struct {
+		Count int               `json:"count"`
+		Data  []ExpressDelivery `json:"data"`
+	}
+
+	// ExpressContact 发件人/收件人信息
+	ExpressContact struct {
+		Name     string `json:"name"`                // 姓名
+		Tel      string `json:"tel,omitempty"`       // 座机，与手机二选一
+		Mobile   string `json:"mobile,omitempty"`    // 手机，与座机二选一
+		Company  string `json:"company,omitempty"`   // 公司名称
+		PostCode string `json:"post_code,omitempty"` // 邮编
+		Country  string `json:"country,omitempty"`   // 国家
+		Province string `json:"province"`            // 省份
+		City     string `json:"city"`                // 市/地区
+		Area     string `json:"area"`                // 区/县
+		Address  string `json:"address"`             // 详细地址
+	}
+
+	// ExpressCargo 包裹信息
+	ExpressCargo struct {
+		Count      int                `json:"count"`       // 包裹数量
+		Weight     float64            `json:"weight"`      // 包裹总重量，单位是千克
+		SpaceX     float64            `json:"space_x"`     // 包裹长度，单位厘米
+		SpaceY     float64            `json:"space_y"`     // 包裹宽度，单位厘米
+		SpaceZ     float64            `json:"space_z"`     // 包裹高度，单位厘米
+		DetailList []ExpressCargoItem `json:"detail_list"` // 包裹中商品详情列表
+	}
+
+	// ExpressCargoItem 包裹中的商品
+	ExpressCargoItem struct {
+		Name  string `json:"name"`  // 商品名
+		Count int    `json:"count"` // 商品数量
+	}
+
+	// ExpressShop 商品信息，会展示到物流服务通知和电子面单中
+	ExpressShop struct {
+		WxaPath    string `json:"wxa_path"`    // 商家小程序的路径
+		ImgURL     string `json:"img_url"`     // 商品缩略图url
+		GoodsName  string `json:"goods_name"`  // 商品名称
+		GoodsCount int    `json:"goods_count"` // 商品数量
+	}
+
+	// ExpressInsured 保价信息
+	ExpressInsured struct {
+		UseInsured   int `json:"use_insured"`   // 是否保价，0不保价，1保价
+		InsuredValue int `json:"insured_value"` // 保价金额，单位是分
+	}
+
+	// ExpressService 服务类型
+	ExpressService struct {
+		ServiceType int    `json:"service_type"` // 服务类型ID
+		ServiceName string `json:"service_name"` // 服务名称
+	}
+
+	// ExpressOrderAdd 生成运单参数
+	ExpressOrderAdd struct {
+		AddSource    int             `json:"add_source"`              // 订单来源，见ExpressSource*
+		WxAppID      string          `json:"wx_appid,omitempty"`      // App或H5的appid，add_source=2时必填
+		OrderID      string          `json:"order_id"`                // 订单ID，须保证全局唯一
+		OpenID       string          `json:"openid,omitempty"`        // 用户openid，add_source=2时不填
+		DeliveryID   string          `json:"delivery_id"`             // 快递公司ID
+		BizID        string          `json:"biz_id"`                  // 快递客户编码或者现付编码
+		CustomRemark string          `json:"custom_remark,omitempty"` // 快递备注信息
+		TagID        int             `json:"tagid,omitempty"`         // 订单标签id，用于平台型小程序区分平台上的入驻方
+		Sender       *ExpressContact `json:"sender"`                  // 发件人信息
+		Receiver     *ExpressContact `json:"receiver"`                // 收件人信息
+		Cargo        *ExpressCargo   `json:"cargo"`                   // 包裹信息
+		Shop         *ExpressShop    `json:"shop"`                    // 商品信息
+		Insured      *ExpressInsured `json:"insured"`                 // 保价信息
+		Service      *ExpressService `json:"service"`                 // 服务类型
+		ExpectTime   int64           `json:"expect_time,omitempty"`   // 预期的上门揽件时间，Unix时间戳
+		TakeMode     int             `json:"take_mode,omitempty"`     // 分单策略，0线下网点签约，1总部签约结算
+	}
+
+	// RespExpressOrderAdd 生成运单结果
+	RespExpressOrderAdd struct {
+		OrderID     string               `json:"order_id"`     // 订单ID
+		WaybillID   string               `json:"waybill_id"`   // 运单ID
+		WaybillData []ExpressWaybillData `json:"waybill_data"` // 运单信息
+	}
+
+	// ExpressWaybillData 运单信息
+	ExpressWaybillData struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+
+	// ExpressOrderQuery 查询/取消运单参数
+	ExpressOrderQuery struct {
+		OrderID      string `json:"order_id"`                // 订单ID
+		OpenID       string `json:"openid,omitempty"`        // 用户openid
+		DeliveryID   string `json:"delivery_id"`             // 快递公司ID
+		WaybillID    string `json:"waybill_id"`              // 运单ID
+		PrintType    int    `json:"print_type,omitempty"`    // 获取运单时，该参数为1时返回带打印员信息的面单
+		CustomRemark string `json:"custom_remark,omitempty"` // 获取运单时，面单上的快递备注信息
+	}
+
+	// RespExpressOrder 运单详情
+	RespExpressOrder struct {
+		PrintHTML   string               `json:"print_html"`   // 运单html的base64结果
+		WaybillData []ExpressWaybillData `json:"waybill_data"` // 运单信息
+		DeliveryID  string               `json:"delivery_id"`
+		WaybillID   string               `json:"waybill_id"`
+		OrderID     string               `json:"order_id"`
+		OrderStatus int                  `json:"order_status"` // 运单状态，0正常，1取消
+	}
+
+	// RespExpressCancel 取消运单结果
+	RespExpressCancel struct {
+		DeliveryResultCode int    `json:"delivery_resultcode"` // 快递公司错误码
+		DeliveryResultMsg  string `json:"delivery_resultmsg"`  // 快递公司错误信息
+	}
+
+	// ExpressPathAction 轨迹节点
+	ExpressPathAction struct {
+		ActionTime int64  `json:"action_time"` // 轨迹节点Unix时间戳
+		ActionType int    `json:"action_type"` // 轨迹节点类型，见ExpressPath*
+		ActionMsg  string `json:"action_msg"`  // 轨迹节点详情
+	}
+
+	// RespExpressPath 运单轨迹
+	RespExpressPath struct {
+		OpenID       string              `json:"openid"`
+		DeliveryID   string              `json:"delivery_id"`
+		WaybillID    string              `json:"waybill_id"`
+		PathItemNum  int                 `json:"path_item_num"`
+		PathItemList []ExpressPathAction `json:"path_item_list"`
+	}
+
+	// RespExpressPrinter 打印员列表
+	RespExpressPrinter struct {
+		Count     int      `json:"count"`
+		OpenID    []string `json:"openid"`
+		TagIDList []string `json:"tagid_list"`
+	}
+
+	// RespExpressQuota 电子面单余额
+	RespExpressQuota struct {
+		QuotaNum int `json:"quota_num"`
+	}
+)
+
+// GetAllDelivery 获取支持的快递公司列表
+func (api *WechatAPI) GetAllDelivery() (*RespExpressDeliveryList, *WechatResp, []error) {
+	respData := &RespExpressDeliveryList{}
+	resp, errs := api.Request(&option{
+		method:    "GET",
+		url:       "/cgi-bin/express/business/delivery/getall",
+		withToken: true,
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// AddExpressOrder 生成运单
+func (api *WechatAPI) AddExpressOrder(order *ExpressOrderAdd) (*RespExpressOrderAdd, *WechatResp, []error) {
+	respData := &RespExpressOrderAdd{}
+	resp, errs := api.Request(&option{
+		method:    "POST",
+		url:       "/cgi-bin/express/business/order/add",
+		withToken: true,
+		body:      order,
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// CancelExpressOrder 取消运单
+func (api *WechatAPI) CancelExpressOrder(order *ExpressOrderQuery) (*RespExpressCancel, *WechatResp, []error) {
+	respData := &RespExpressCancel{}
+	resp, errs := api.Request(&option{
+		method:    "POST",
+		url:       "/cgi-bin/express/business/order/cancel",
+		withToken: true,
+		body:      order,
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// GetExpressOrder 获取运单数据
+func (api *WechatAPI) GetExpressOrder(order *ExpressOrderQuery) (*RespExpressOrder, *WechatResp, []error) {
+	respData := &RespExpressOrder{}
+	resp, errs := api.Request(&option{
+		method:    "POST",
+		url:       "/cgi-bin/express/business/order/get",
+		withToken: true,
+		body:      order,
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// GetExpressPath 查询运单轨迹
+func (api *WechatAPI) GetExpressPath(order *ExpressOrderQuery) (*RespExpressPath, *WechatResp, []error) {
+	respData := &RespExpressPath{}
+	resp, errs := api.Request(&option{
+		method:    "POST",
+		url:       "/cgi-bin/express/business/path/get",
+		withToken: true,
+		body:      order,
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// GetAllPrinter 获取打印员列表
+func (api *WechatAPI) GetAllPrinter() (*RespExpressPrinter, *WechatResp, []error) {
+	respData := &RespExpressPrinter{}
+	resp, errs := api.Request(&option{
+		method:    "GET",
+		url:       "/cgi-bin/express/business/printer/getall",
+		withToken: true,
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// GetExpressQuota 获取电子面单余额，仅在使用加盟类快递公司时调用
+func (api *WechatAPI) GetExpressQuota(deliveryID, bizID string) (*RespExpressQuota, *WechatResp, []error) {
+	respData := &RespExpressQuota{}
+	resp, errs := api.Request(&option{
+		method:    "POST",
+		url:       "/cgi-bin/express/business/quota/get",
+		withToken: true,
+		body: map[string]string{
+			"delivery_id": deliveryID,
+			"biz_id":      bizID,
+		},
+	}, respData)
+
+	return respData, resp, errs
+}
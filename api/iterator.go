@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+)
+
+type (
+	// pageIterator 分页迭代器的公共部分，按需拉取下一页
+	// fetch拉取下一页并返回本页数量及是否还有更多
+	pageIterator struct {
+		index int
+		size  int
+		done  bool
+		err   error
+		fetch func() (size int, more bool, resp *WechatResp, errs []error)
+	}
+)
+
+func newPageIterator(fetch func() (int, bool, *WechatResp, []error)) pageIterator {
+	return pageIterator{index: -1, fetch: fetch}
+}
+
+// Next 移动到下一项，没有更多或出错时返回false
+func (it *pageIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	it.index++
+	for it.index >= it.size {
+		if it.done {
+			return false
+		}
+
+		size, more, resp, errs := it.fetch()
+		if err := respError(resp, errs); err != nil {
+			it.err = err
+			return false
+		}
+		it.index, it.size, it.done = 0, size, !more || size == 0
+	}
+
+	return true
+}
+
+// valid 当前位置是否有效，Next之前、Next返回false之后均无效
+// length为当前页实际的数据条数
+func (it *pageIterator) valid(length int) bool {
+	return it.err == nil && it.index >= 0 && it.index < it.size && it.index < length
+}
+
+// Err 迭代过程中的错误
+func (it *pageIterator) Err() error {
+	return it.err
+}
+
+// respError 将接口调用结果转换为error
+func respError(resp *WechatResp, errs []error) error {
+	if len(errs) != 0 {
+		return errs[0]
+	} else if resp != nil && resp.ErrCode != 0 {
+		return fmt.Errorf("errcode:%d errmsg:%s", resp.ErrCode, resp.ErrMsg)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"errors"
+	"testing"
+)
+
+// pages 模拟分页接口，每次返回一页
+func pages(sizes []int, fail int) (*[]int, func() (int, bool, *WechatResp, []error)) {
+	calls := 0
+	fetched := &[]int{}
+	return fetched, func() (int, bool, *WechatResp, []error) {
+		calls++
+		if calls == fail {
+			return 0, false, nil, []error{errors.New("network error")}
+		}
+
+		size := sizes[calls-1]
+		*fetched = append(*fetched, size)
+		return size, calls < len(sizes), &WechatResp{}, nil
+	}
+}
+
+func count(it *pageIterator) int {
+	total := 0
+	for it.Next() {
+		total++
+	}
+
+	return total
+}
+
+func TestPageIteratorMultiPage(t *testing.T) {
+	fetched, fetch := pages([]int{2, 2, 1}, 0)
+	it := newPageIterator(fetch)
+
+	if total := count(&it); total != 5 || it.Err() != nil || len(*fetched) != 3 {
+		t.Fatalf("iterated %d items over %v pages, err %v", total, *fetched, it.Err())
+	}
+	if it.Next() || it.valid(2) {
+		t.Fatal("iterator should stay exhausted")
+	}
+}
+
+func TestPageIteratorEmptyFirstPage(t *testing.T) {
+	// 首页为空时即使接口声明还有更多也停止，避免死循环
+	fetched, fetch := pages([]int{0, 2}, 0)
+	it := newPageIterator(fetch)
+
+	if total := count(&it); total != 0 || it.Err() != nil || len(*fetched) != 1 {
+		t.Fatalf("iterated %d items over %v pages, err %v", total, *fetched, it.Err())
+	}
+}
+
+func TestPageIteratorErrorOnSecondPage(t *testing.T) {
+	_, fetch := pages([]int{2, 2}, 2)
+	it := newPageIterator(fetch)
+
+	if total := count(&it); total != 2 || it.Err() == nil {
+		t.Fatalf("iterated %d items, err %v", total, it.Err())
+	}
+	if it.Next() || it.valid(2) {
+		t.Fatal("iterator should stop after an error")
+	}
+}
+
+func TestPageIteratorBusinessError(t *testing.T) {
+	it := newPageIterator(func() (int, bool, *WechatResp, []error) {
+		return 0, false, &WechatResp{ErrCode: 40001, ErrMsg: "invalid credential"}, nil
+	})
+
+	if it.Next() || it.Err() == nil {
+		t.Fatalf("errcode should stop the iterator, err %v", it.Err())
+	}
+}
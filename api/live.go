@@ -0,0 +1,390 @@
+package api
+
+import (
+	"strconv"
+)
+
+const (
+	// LiveStatusLiving 直播中
+	LiveStatusLiving = 101
+	// LiveStatusNotStarted 未开始
+	LiveStatusNotStarted = 102
+	// LiveStatusEnded 已结束
+	LiveStatusEnded = 103
+	// LiveStatusBanned 禁播
+	LiveStatusBanned = 104
+	// LiveStatusPaused 暂停
+	LiveStatusPaused = 105
+	// LiveStatusAbnormal 异常
+	LiveStatusAbnormal = 106
+	// LiveStatusExpired 已过期
+	LiveStatusExpired = 107
+)
+
+const (
+	// LiveTypeMobile 手机直播
+	LiveTypeMobile = 0
+	// LiveTypePush 推流直播
+	LiveTypePush = 1
+)
+
+const (
+	// liveErrNoRoom 获取直播间列表时，未创建直播间
+	liveErrNoRoom = 1
+)
+
+type (
+	// LiveRoom 直播间创建及编辑参数，编辑时需填写ID
+	LiveRoom struct {
+		ID              int    `json:"id,omitempty"`              // 直播间id，编辑时必填
+		Name            string `json:"name"`                      // 直播间名字，最短3个汉字，最长17个汉字
+		CoverImg        string `json:"coverImg"`                  // 背景图的临时素材media_id
+		StartTime       int64  `json:"startTime"`                 // 开播时间，Unix时间戳
+		EndTime         int64  `json:"endTime"`                   // 结束时间，Unix时间戳
+		AnchorName      string `json:"anchorName"`                // 主播昵称
+		AnchorWechat    string `json:"anchorWechat"`              // 主播微信号，需通过实名验证
+		SubAnchorWechat string `json:"subAnchorWechat,omitempty"` // 主播副号微信号
+		CreaterWechat   string `json:"createrWechat,omitempty"`   // 创建者微信号
+		ShareImg        string `json:"shareImg"`                  // 分享图的临时素材media_id
+		FeedsImg        string `json:"feedsImg,omitempty"`        // 购物直播频道封面图的临时素材media_id
+		IsFeedsPublic   int    `json:"isFeedsPublic"`             // 是否开启官方收录，1开启，0关闭
+		Type            int    `json:"type"`                      // 直播间类型，见LiveType*
+		CloseLike       int    `json:"closeLike"`                 // 是否关闭点赞，1关闭
+		CloseGoods      int    `json:"closeGoods"`                // 是否关闭货架，1关闭
+		CloseComment    int    `json:"closeComment"`              // 是否关闭评论，1关闭
+		CloseReplay     int    `json:"closeReplay"`               // 是否关闭回放，1关闭
+		CloseShare      int    `json:"closeShare"`                // 是否关闭分享，1关闭
+		CloseKf         int    `json:"closeKf"`                   // 是否关闭客服，1关闭
+	}
+
+	// RespLiveRoomCreate 创建直播间结果
+	RespLiveRoomCreate struct {
+		RoomID    int    `json:"roomId"`     // 房间ID
+		QRCodeURL string `json:"qrcode_url"` // 主播未实名认证时返回的小程序码
+	}
+
+	// LiveRoomInfo 直播间信息
+	LiveRoomInfo struct {
+		Name          string          `json:"name"`            // 直播间名称
+		RoomID        int             `json:"roomid"`          // 直播间id
+		CoverImg      string          `json:"cover_img"`       // 背景图
+		ShareImg      string          `json:"share_img"`       // 分享图
+		FeedsImg      string          `json:"feeds_img"`       // 官方收录封面
+		LiveStatus    int             `json:"live_status"`     // 直播间状态，见LiveStatus*
+		StartTime     int64           `json:"start_time"`      // 开播时间
+		EndTime       int64           `json:"end_time"`        // 结束时间
+		AnchorName    string          `json:"anchor_name"`     // 主播名
+		Goods         []LiveRoomGoods `json:"goods"`           // 直播间的商品
+		LiveType      int             `json:"live_type"`       // 直播类型，见LiveType*
+		CloseLike     int             `json:"close_like"`      // 是否关闭点赞
+		CloseGoods    int             `json:"close_goods"`     // 是否关闭货架
+		CloseComment  int             `json:"close_comment"`   // 是否关闭评论
+		CloseKf       int             `json:"close_kf"`        // 是否关闭客服
+		CloseReplay   int             `json:"close_replay"`    // 是否关闭回放
+		IsFeedsPublic int             `json:"is_feeds_public"` // 是否开启官方收录
+		CreaterOpenID string          `json:"creater_openid"`  // 创建者openid
+	}
+
+	// LiveRoomGoods 直播间的商品
+	LiveRoomGoods struct {
+		GoodsID         int     `json:"goods_id"`
+		CoverImg        string  `json:"cover_img"`
+		URL             string  `json:"url"`
+		Name            string  `json:"name"`
+		Price           float64 `json:"price"`
+		Price2          float64 `json:"price2"`
+		PriceType       int     `json:"price_type"`
+		ThirdPartyAppID string  `json:"third_party_appid"`
+	}
+
+	// RespLiveRoomList 直播间列表
+	RespLiveRoomList struct {
+		RoomInfo []LiveRoomInfo `json:"room_info"`
+		Total    int            `json:"total"`
+	}
+
+	// LiveRoomIterator 直播间列表迭代器
+	// examples:
+	// it := app.API.LiveRooms(100)
+	// for it.Next() { room := it.Room() }
+	// if err := it.Err(); err != nil {}
+	LiveRoomIterator struct {
+		pageIterator
+		rooms []LiveRoomInfo
+	}
+
+	// RespLivePushURL 直播间推流地址
+	RespLivePushURL struct {
+		PushAddr string `json:"pushAddr"`
+	}
+
+	// RespLiveShareCode 直播间分享二维码
+	RespLiveShareCode struct {
+		CdnURL    string `json:"cdnUrl"`    // 分享二维码地址
+		PagePath  string `json:"pagePath"`  // 分享路径
+		PosterURL string `json:"posterUrl"` // 分享海报地址
+	}
+
+	// LiveAssistant 直播间小助手
+	LiveAssistant struct {
+		Username  string `json:"username"`            // 微信号
+		Nickname  string `json:"nickname"`            // 昵称
+		Timestamp int64  `json:"timestamp,omitempty"` // 修改时间
+		HeadImg   string `json:"headimg,omitempty"`   // 头像
+		OpenID    string `json:"openid,omitempty"`
+	}
+
+	// RespLiveAssistantList 直播间小助手列表
+	RespLiveAssistantList struct {
+		List     []LiveAssistant `json:"list"`
+		Count    int             `json:"count"`    // 小助手个数
+		MaxCount int             `json:"maxCount"` // 小助手最大个数
+	}
+
+	// LiveFollower 长期订阅用户
+	LiveFollower struct {
+		RoomID     int    `json:"room_id"`     // 用户订阅的直播间id
+		OpenID     string `json:"openid"`      // 用户openid
+		CreateTime int64  `json:"create_time"` // 订阅时间
+		RoomStatus int    `json:"room_status"` // 直播间状态，见LiveStatus*
+	}
+
+	// RespLiveFollowerList 长期订阅用户列表
+	RespLiveFollowerList struct {
+		Followers []LiveFollower `json:"followers"`
+		PageBreak int            `json:"page_break"` // 翻页标记，为0时没有更多
+	}
+
+	// LiveFollowerIterator 长期订阅用户迭代器
+	LiveFollowerIterator struct {
+		pageIterator
+		followers []LiveFollower
+	}
+
+	// RespLivePushMessage 长期订阅群发结果
+	RespLivePushMessage struct {
+		MessageID string `json:"message_id"`
+	}
+)
+
+// CreateLiveRoom 创建直播间
+func (api *WechatAPI) CreateLiveRoom(room *LiveRoom) (*RespLiveRoomCreate, *WechatResp, []error) {
+	respData := &RespLiveRoomCreate{}
+	resp, errs := api.Request(&option{
+		method:    "POST",
+		url:       "/wxaapi/broadcast/room/create",
+		withToken: true,
+		body:      room,
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// EditLiveRoom 编辑直播间，直播开始后不可编辑
+func (api *WechatAPI) EditLiveRoom(room *LiveRoom) (*WechatResp, []error) {
+	return api.Request(&option{
+		method:    "POST",
+		url:       "/wxaapi/broadcast/room/editroom",
+		withToken: true,
+		body:      room,
+	})
+}
+
+// DeleteLiveRoom 删除直播间
+func (api *WechatAPI) DeleteLiveRoom(roomID int) (*WechatResp, []error) {
+	return api.Request(&option{
+		method:    "POST",
+		url:       "/wxaapi/broadcast/room/deleteroom",
+		withToken: true,
+		body: map[string]int{
+			"id": roomID,
+		},
+	})
+}
+
+// GetLiveRooms 获取直播间列表，start从0开始，limit最大100
+// 未创建直播间时返回空列表
+func (api *WechatAPI) GetLiveRooms(start, limit int) (*RespLiveRoomList, *WechatResp, []error) {
+	respData := &RespLiveRoomList{}
+	resp, errs := api.Request(&option{
+		method:    "POST",
+		url:       "/wxa/business/getliveinfo",
+		withToken: true,
+		body: map[string]int{
+			"start": start,
+			"limit": limit,
+		},
+	}, respData)
+
+	if resp != nil && resp.ErrCode == liveErrNoRoom {
+		resp.ErrCode = 0
+	}
+
+	return respData, resp, errs
+}
+
+// LiveRooms 按页遍历直播间列表，limit为每页数量，最大100
+func (api *WechatAPI) LiveRooms(limit int) *LiveRoomIterator {
+	start := 0
+	it := &LiveRoomIterator{}
+	it.pageIterator = newPageIterator(func() (int, bool, *WechatResp, []error) {
+		respData, resp, errs := api.GetLiveRooms(start, limit)
+		it.rooms = respData.RoomInfo
+		start += len(respData.RoomInfo)
+
+		return len(respData.RoomInfo), start < respData.Total, resp, errs
+	})
+
+	return it
+}
+
+// Room 当前的直播间，Next返回true之前及返回false之后为nil
+func (it *LiveRoomIterator) Room() *LiveRoomInfo {
+	if !it.valid(len(it.rooms)) {
+		return nil
+	}
+
+	return &it.rooms[it.index]
+}
+
+// GetLivePushURL 获取直播间推流地址，仅推流直播可用
+func (api *WechatAPI) GetLivePushURL(roomID int) (*RespLivePushURL, *WechatResp, []error) {
+	respData := &RespLivePushURL{}
+	resp, errs := api.Request(&option{
+		method:    "GET",
+		url:       "/wxaapi/broadcast/room/getpushurl",
+		withToken: true,
+		query: map[string]string{
+			"roomId": strconv.Itoa(roomID),
+		},
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// GetLiveShareCode 获取直播间分享二维码，params为自定义参数
+func (api *WechatAPI) GetLiveShareCode(roomID int, params string) (*RespLiveShareCode, *WechatResp, []error) {
+	respData := &RespLiveShareCode{}
+	resp, errs := api.Request(&option{
+		method:    "GET",
+		url:       "/wxaapi/broadcast/room/getsharedcode",
+		withToken: true,
+		query: map[string]string{
+			"roomId": strconv.Itoa(roomID),
+			"params": params,
+		},
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// AddLiveAssistants 添加直播间小助手
+func (api *WechatAPI) AddLiveAssistants(roomID int, users []LiveAssistant) (*WechatResp, []error) {
+	return api.Request(&option{
+		method:    "POST",
+		url:       "/wxaapi/broadcast/room/addassistant",
+		withToken: true,
+		body: map[string]interface{}{
+			"roomId": roomID,
+			"users":  users,
+		},
+	})
+}
+
+// ModifyLiveAssistant 修改直播间小助手昵称
+func (api *WechatAPI) ModifyLiveAssistant(roomID int, username, nickname string) (*WechatResp, []error) {
+	return api.Request(&option{
+		method:    "POST",
+		url:       "/wxaapi/broadcast/room/modifyassistant",
+		withToken: true,
+		body: map[string]interface{}{
+			"roomId":   roomID,
+			"username": username,
+			"nickname": nickname,
+		},
+	})
+}
+
+// RemoveLiveAssistant 删除直播间小助手
+func (api *WechatAPI) RemoveLiveAssistant(roomID int, username string) (*WechatResp, []error) {
+	return api.Request(&option{
+		method:    "POST",
+		url:       "/wxaapi/broadcast/room/removeassistant",
+		withToken: true,
+		body: map[string]interface{}{
+			"roomId":   roomID,
+			"username": username,
+		},
+	})
+}
+
+// GetLiveAssistants 查询直播间小助手
+func (api *WechatAPI) GetLiveAssistants(roomID int) (*RespLiveAssistantList, *WechatResp, []error) {
+	respData := &RespLiveAssistantList{}
+	resp, errs := api.Request(&option{
+		method:    "GET",
+		url:       "/wxaapi/broadcast/room/getassistantlist",
+		withToken: true,
+		query: map[string]string{
+			"roomId": strconv.Itoa(roomID),
+		},
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// GetLiveFollowers 获取长期订阅用户，pageBreak为翻页标记，获取第一页时传0
+func (api *WechatAPI) GetLiveFollowers(limit, pageBreak int) (*RespLiveFollowerList, *WechatResp, []error) {
+	respData := &RespLiveFollowerList{}
+	resp, errs := api.Request(&option{
+		method:    "POST",
+		url:       "/wxa/business/get_wxa_followers",
+		withToken: true,
+		body: map[string]int{
+			"limit":      limit,
+			"page_break": pageBreak,
+		},
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// LiveFollowers 按页遍历长期订阅用户，limit为每页数量，最大2000
+func (api *WechatAPI) LiveFollowers(limit int) *LiveFollowerIterator {
+	pageBreak := 0
+	it := &LiveFollowerIterator{}
+	it.pageIterator = newPageIterator(func() (int, bool, *WechatResp, []error) {
+		respData, resp, errs := api.GetLiveFollowers(limit, pageBreak)
+		it.followers = respData.Followers
+		pageBreak = respData.PageBreak
+
+		return len(respData.Followers), pageBreak != 0, resp, errs
+	})
+
+	return it
+}
+
+// Follower 当前的长期订阅用户，Next返回true之前及返回false之后为nil
+func (it *LiveFollowerIterator) Follower() *LiveFollower {
+	if !it.valid(len(it.followers)) {
+		return nil
+	}
+
+	return &it.followers[it.index]
+}
+
+// PushLiveMessage 向长期订阅用户群发直播间开始事件，每次最多发送1万个用户
+func (api *WechatAPI) PushLiveMessage(roomID int, openids []string) (*RespLivePushMessage, *WechatResp, []error) {
+	respData := &RespLivePushMessage{}
+	resp, errs := api.Request(&option{
+		method:    "POST",
+		url:       "/wxa/business/push_message",
+		withToken: true,
+		body: map[string]interface{}{
+			"room_id":     roomID,
+			"user_openid": openids,
+		},
+	}, respData)
+
+	return respData, resp, errs
+}
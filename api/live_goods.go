@@ -0,0 +1,196 @@
+package api
+
+import (
+	"strconv"
+)
+
+const (
+	// LivePriceFixed 一口价，只需填写price
+	LivePriceFixed = 1
+	// LivePriceRange 价格区间，price为左边界，price2为右边界
+	LivePriceRange = 2
+	// LivePriceDiscount 折扣价，price为原价，price2为现价
+	LivePriceDiscount = 3
+)
+
+const (
+	// LiveGoodsUnaudited 未审核
+	LiveGoodsUnaudited = 0
+	// LiveGoodsAuditing 审核中
+	LiveGoodsAuditing = 1
+	// LiveGoodsApproved 审核通过
+	LiveGoodsApproved = 2
+	// LiveGoodsRejected 审核驳回
+	LiveGoodsRejected = 3
+)
+
+type (
+	// LiveGoods 直播商品添加及更新参数，更新时需填写GoodsID
+	LiveGoods struct {
+		GoodsID         int     `json:"goodsId,omitempty"`         // 商品ID，更新时必填
+		CoverImgURL     string  `json:"coverImgUrl,omitempty"`     // 商品图片的临时素材media_id
+		Name            string  `json:"name,omitempty"`            // 商品名称，最长14个汉字
+		PriceType       int     `json:"priceType,omitempty"`       // 价格类型，见LivePrice*
+		Price           float64 `json:"price,omitempty"`           // 价格，单位元
+		Price2          float64 `json:"price2,omitempty"`          // 价格区间右边界或现价，单位元
+		URL             string  `json:"url,omitempty"`             // 商品详情页的小程序路径
+		ThirdPartyAppID string  `json:"thirdPartyAppid,omitempty"` // 商品详情页所属的小程序appid
+	}
+
+	// RespLiveGoodsAdd 添加直播商品结果
+	RespLiveGoodsAdd struct {
+		GoodsID int `json:"goodsId"` // 商品ID
+		AuditID int `json:"auditId"` // 审核单ID
+	}
+
+	// RespLiveGoodsAudit 重新提交审核结果
+	RespLiveGoodsAudit struct {
+		AuditID int `json:"auditId"` // 审核单ID
+	}
+
+	// LiveGoodsInfo 商品库中的直播商品
+	LiveGoodsInfo struct {
+		GoodsID         int     `json:"goodsId"`
+		CoverImgURL     string  `json:"coverImgUrl"`
+		Name            string  `json:"name"`
+		Price           float64 `json:"price"`
+		Price2          float64 `json:"price2"`
+		PriceType       int     `json:"priceType"`
+		URL             string  `json:"url"`
+		ThirdPartyTag   int     `json:"thirdPartyTag"` // 1、2表示是为api添加商品，否则是直播控制台添加的商品
+		ThirdPartyAppID string  `json:"thirdPartyAppid"`
+	}
+
+	// RespLiveGoodsList 直播商品列表
+	RespLiveGoodsList struct {
+		Goods []LiveGoodsInfo `json:"goods"`
+		Total int             `json:"total"`
+	}
+
+	// LiveGoodsIterator 直播商品列表迭代器
+	LiveGoodsIterator struct {
+		pageIterator
+		goods []LiveGoodsInfo
+	}
+)
+
+// AddLiveGoods 添加并提审直播商品
+func (api *WechatAPI) AddLiveGoods(goods *LiveGoods) (*RespLiveGoodsAdd, *WechatResp, []error) {
+	respData := &RespLiveGoodsAdd{}
+	resp, errs := api.Request(&option{
+		method:    "POST",
+		url:       "/wxaapi/broadcast/goods/add",
+		withToken: true,
+		body: map[string]*LiveGoods{
+			"goodsInfo": goods,
+		},
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// UpdateLiveGoods 更新直播商品，审核通过的商品仅允许更新价格类型与价格
+func (api *WechatAPI) UpdateLiveGoods(goods *LiveGoods) (*WechatResp, []error) {
+	return api.Request(&option{
+		method:    "POST",
+		url:       "/wxaapi/broadcast/goods/update",
+		withToken: true,
+		body: map[string]*LiveGoods{
+			"goodsInfo": goods,
+		},
+	})
+}
+
+// DeleteLiveGoods 删除直播商品
+func (api *WechatAPI) DeleteLiveGoods(goodsID int) (*WechatResp, []error) {
+	return api.Request(&option{
+		method:    "POST",
+		url:       "/wxaapi/broadcast/goods/delete",
+		withToken: true,
+		body: map[string]int{
+			"goodsId": goodsID,
+		},
+	})
+}
+
+// AuditLiveGoods 重新提交审核未审核的直播商品
+func (api *WechatAPI) AuditLiveGoods(goodsID int) (*RespLiveGoodsAudit, *WechatResp, []error) {
+	respData := &RespLiveGoodsAudit{}
+	resp, errs := api.Request(&option{
+		method:    "POST",
+		url:       "/wxaapi/broadcast/goods/audit",
+		withToken: true,
+		body: map[string]int{
+			"goodsId": goodsID,
+		},
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// ResetLiveGoodsAudit 撤回审核中的直播商品
+func (api *WechatAPI) ResetLiveGoodsAudit(goodsID, auditID int) (*WechatResp, []error) {
+	return api.Request(&option{
+		method:    "POST",
+		url:       "/wxaapi/broadcast/goods/resetaudit",
+		withToken: true,
+		body: map[string]int{
+			"goodsId": goodsID,
+			"auditId": auditID,
+		},
+	})
+}
+
+// ImportLiveGoods 导入审核通过的商品到直播间
+func (api *WechatAPI) ImportLiveGoods(roomID int, goodsIDs []int) (*WechatResp, []error) {
+	return api.Request(&option{
+		method:    "POST",
+		url:       "/wxaapi/broadcast/room/addgoods",
+		withToken: true,
+		body: map[string]interface{}{
+			"roomId": roomID,
+			"ids":    goodsIDs,
+		},
+	})
+}
+
+// GetLiveGoods 获取商品库中的直播商品，status见LiveGoods*，limit最大100
+func (api *WechatAPI) GetLiveGoods(status, offset, limit int) (*RespLiveGoodsList, *WechatResp, []error) {
+	respData := &RespLiveGoodsList{}
+	resp, errs := api.Request(&option{
+		method:    "GET",
+		url:       "/wxaapi/broadcast/goods/getapproved",
+		withToken: true,
+		query: map[string]string{
+			"status": strconv.Itoa(status),
+			"offset": strconv.Itoa(offset),
+			"limit":  strconv.Itoa(limit),
+		},
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// LiveGoods 按页遍历商品库中指定审核状态的直播商品
+func (api *WechatAPI) LiveGoods(status, limit int) *LiveGoodsIterator {
+	offset := 0
+	it := &LiveGoodsIterator{}
+	it.pageIterator = newPageIterator(func() (int, bool, *WechatResp, []error) {
+		respData, resp, errs := api.GetLiveGoods(status, offset, limit)
+		it.goods = respData.Goods
+		offset += len(respData.Goods)
+
+		return len(respData.Goods), offset < respData.Total, resp, errs
+	})
+
+	return it
+}
+
+// Goods 当前的直播商品，Next返回true之前及返回false之后为nil
+func (it *LiveGoodsIterator) Goods() *LiveGoodsInfo {
+	if !it.valid(len(it.goods)) {
+		return nil
+	}
+
+	return &it.goods[it.index]
+}
@@ -0,0 +1,77 @@
+package api_test
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"testing"
+
+	"github.com/amazing-gao/applet/api"
+	"github.com/amazing-gao/applet/applettest"
+)
+
+func newTestAPI(t *testing.T) (*api.WechatAPI, *applettest.Server) {
+	srv := applettest.NewServer("wx0000000000000000", "secret")
+
+	wechatAPI, err := api.NewWechatAPI("wx0000000000000000",
+		api.WithAppKey("secret"),
+		api.WithBaseURL(srv.URL),
+		api.WithLogger(log.New(ioutil.Discard, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wechatAPI.RenewToken()
+
+	return wechatAPI, srv
+}
+
+func newLiveAPI(t *testing.T, rooms [][]api.LiveRoomInfo, total int) (*api.WechatAPI, *applettest.Server) {
+	wechatAPI, srv := newTestAPI(t)
+	page := 0
+	srv.Handle("/wxa/business/getliveinfo", true, func(call *applettest.Call) (int, interface{}) {
+		if len(rooms) == 0 {
+			return http.StatusOK, map[string]interface{}{"errcode": 1, "errmsg": "no room"}
+		}
+
+		page++
+		return http.StatusOK, &api.RespLiveRoomList{RoomInfo: rooms[page-1], Total: total}
+	})
+
+	return wechatAPI, srv
+}
+
+func TestLiveRooms(t *testing.T) {
+	wechatAPI, srv := newLiveAPI(t, [][]api.LiveRoomInfo{{{RoomID: 1}, {RoomID: 2}}, {{RoomID: 3}}}, 3)
+	defer srv.Close()
+
+	it := wechatAPI.LiveRooms(2)
+	if it.Room() != nil {
+		t.Fatal("room before Next should be nil")
+	}
+
+	ids := []int{}
+	for it.Next() {
+		ids = append(ids, it.Room().RoomID)
+	}
+	if it.Err() != nil || len(ids) != 3 || ids[2] != 3 {
+		t.Fatalf("rooms got %v %v", ids, it.Err())
+	}
+	if it.Room() != nil {
+		t.Fatal("room after the last Next should be nil")
+	}
+}
+
+func TestLiveRoomsWithoutRoom(t *testing.T) {
+	wechatAPI, srv := newLiveAPI(t, nil, 0)
+	defer srv.Close()
+
+	respData, resp, errs := wechatAPI.GetLiveRooms(0, 100)
+	if len(errs) != 0 || resp.ErrCode != 0 || len(respData.RoomInfo) != 0 {
+		t.Fatalf("no room got %+v %+v %v", respData, resp, errs)
+	}
+
+	it := wechatAPI.LiveRooms(100)
+	if it.Next() || it.Err() != nil || it.Room() != nil {
+		t.Fatalf("iterating without room got err %v", it.Err())
+	}
+}
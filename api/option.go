@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/amazing-gao/applet/internal/logger"
+)
+
+type (
+	// Option WechatAPI配置项
+	Option func(*WechatAPI) error
+
+	// Logger 日志接口，兼容标准库*log.Logger
+	Logger = logger.Logger
+)
+
+// WithAppKey 设置小程序秘钥
+func WithAppKey(appKey string) Option {
+	return func(api *WechatAPI) error {
+		api.appKey = appKey
+		return nil
+	}
+}
+
+// WithTokenStore 设置token存储器，默认使用内存存储器
+func WithTokenStore(tokenStore WechatTokenStore) Option {
+	return func(api *WechatAPI) error {
+		if tokenStore == nil {
+			return errors.New("token store is nil")
+		}
+		api.apiTokenStore = tokenStore
+		return nil
+	}
+}
+
+// WithHTTPClient 设置http客户端，可用于配置超时
+// 请求时会使用client的副本，client的Transport为空或为*http.Transport，其他RoundTripper会返回错误
+func WithHTTPClient(client *http.Client) Option {
+	return func(api *WechatAPI) error {
+		if client == nil {
+			return errors.New("http client is nil")
+		}
+		if _, ok := client.Transport.(*http.Transport); client.Transport != nil && !ok {
+			return fmt.Errorf("unsupport http client transport: %T", client.Transport)
+		}
+		api.httpClient = client
+		return nil
+	}
+}
+
+// WithLogger 设置日志
+func WithLogger(logger Logger) Option {
+	return func(api *WechatAPI) error {
+		if logger == nil {
+			return errors.New("logger is nil")
+		}
+		api.logger = logger
+		return nil
+	}
+}
+
+// WithRetry 设置网络错误及5xx响应的重试次数和间隔
+// 只有GET请求及可安全重放的查询类POST请求会重试
+func WithRetry(count int, interval time.Duration) Option {
+	return func(api *WechatAPI) error {
+		if count < 0 || interval < 0 {
+			return fmt.Errorf("invalid retry count:%d interval:%s", count, interval)
+		}
+		api.retryCount = count
+		api.retryInterval = interval
+		return nil
+	}
+}
+
+// WithBaseURL 设置接口地址，覆盖默认的https://api.weixin.qq.com/
+func WithBaseURL(baseURL string) Option {
+	return func(api *WechatAPI) error {
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid base url: %s", baseURL)
+		}
+
+		api.apiScheme = u.Scheme
+		api.apiDomain = u.Host
+		api.apiBasePath = u.Path
+		if api.apiBasePath == "" {
+			api.apiBasePath = "/"
+		}
+		return nil
+	}
+}
+
+// WithBefore 设置请求前hook
+func WithBefore(be Before) Option {
+	return func(api *WechatAPI) error {
+		api.before = be
+		return nil
+	}
+}
+
+// WithAfter 设置请求后hook
+func WithAfter(af After) Option {
+	return func(api *WechatAPI) error {
+		api.after = af
+		return nil
+	}
+}
@@ -0,0 +1,37 @@
+package api
+
+type (
+	// SchemeGenerate 获取小程序scheme码参数
+	SchemeGenerate struct {
+		JumpWxa        *SchemeJumpWxa `json:"jump_wxa,omitempty"`        // 跳转到的目标小程序信息
+		IsExpire       bool           `json:"is_expire,omitempty"`       // 到期失效：true，永久有效：false
+		ExpireType     int            `json:"expire_type,omitempty"`     // 0 指定失效时间 1 指定失效天数
+		ExpireTime     int64          `json:"expire_time,omitempty"`     // 失效时间，Unix时间戳
+		ExpireInterval int            `json:"expire_interval,omitempty"` // 失效天数，最多30天
+	}
+
+	// SchemeJumpWxa 跳转到的目标小程序信息
+	SchemeJumpWxa struct {
+		Path       string `json:"path"`                  // 已发布小程序的页面路径，为空时跳转主页
+		Query      string `json:"query"`                 // 进入小程序时的query
+		EnvVersion string `json:"env_version,omitempty"` // release trial develop
+	}
+
+	// RespScheme 小程序scheme码
+	RespScheme struct {
+		OpenLink string `json:"openlink"`
+	}
+)
+
+// GenerateScheme 获取小程序scheme码，适用于短信、邮件、外部网页等拉起小程序
+func (api *WechatAPI) GenerateScheme(scheme *SchemeGenerate) (*RespScheme, *WechatResp, []error) {
+	respData := &RespScheme{}
+	resp, errs := api.Request(&option{
+		method:    "POST",
+		url:       "/wxa/generatescheme",
+		withToken: true,
+		body:      scheme,
+	}, respData)
+
+	return respData, resp, errs
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/amazing-gao/applet/crypto"
+)
+
+const (
+	// RiskSceneRegister 注册场景
+	RiskSceneRegister RiskScene = 0
+	// RiskSceneMarketing 营销作弊场景
+	RiskSceneMarketing RiskScene = 1
+)
+
+const (
+	// RiskRankNone 无风险
+	RiskRankNone RiskRank = iota
+	// RiskRankLow 低风险
+	RiskRankLow
+	// RiskRankMedium 中风险
+	RiskRankMedium
+	// RiskRankHigh 高风险
+	RiskRankHigh
+	// RiskRankSevere 极高风险
+	RiskRankSevere
+)
+
+type (
+	// RiskScene 用户安全等级的应用场景
+	RiskScene int
+
+	// RiskRank 用户风险等级，数值越大风险越高
+	RiskRank int
+
+	// UserRiskRankReq 获取用户安全等级参数
+	UserRiskRankReq struct {
+		AppID        string    `json:"appid"`                   // 小程序appid，为空时使用当前小程序
+		OpenID       string    `json:"openid"`                  // 用户的openid
+		Scene        RiskScene `json:"scene"`                   // 场景
+		MobileNo     string    `json:"mobile_no,omitempty"`     // 用户手机号
+		ClientIP     string    `json:"client_ip"`               // 用户访问源ip
+		EmailAddress string    `json:"email_address,omitempty"` // 用户邮箱地址
+		ExtendedInfo string    `json:"extended_info,omitempty"` // 额外补充信息
+		IsTest       bool      `json:"is_test,omitempty"`       // 是否为测试调用
+	}
+
+	// RespUserRiskRank 用户安全等级
+	RespUserRiskRank struct {
+		RiskRank RiskRank `json:"risk_rank"`
+		UnoinID  int64    `json:"unoin_id"` // 唯一请求标识，标记单次请求
+	}
+
+	// RespUserEncryptKey 用户最近三次的加密key
+	RespUserEncryptKey struct {
+		KeyInfoList []UserEncryptKey `json:"key_info_list"`
+	}
+
+	// UserEncryptKey 用户加密key
+	UserEncryptKey struct {
+		EncryptKey string `json:"encrypt_key"` // 加密key
+		Version    int    `json:"version"`     // key的版本号
+		ExpireIn   int    `json:"expire_in"`   // 剩余有效时间
+		IV         string `json:"iv"`          // 加密iv
+		CreateTime int64  `json:"create_time"` // 创建key的时间戳
+	}
+)
+
+// GetUserRiskRank 根据提交的用户信息数据获取用户的安全等级，无需用户授权
+// req.AppID为空时使用当前小程序的appid，不修改调用方的req
+func (api *WechatAPI) GetUserRiskRank(req *UserRiskRankReq) (*RespUserRiskRank, *WechatResp, []error) {
+	copied := *req
+	if copied.AppID == "" {
+		copied.AppID = api.appID
+	}
+
+	respData := &RespUserRiskRank{}
+	resp, errs := api.Request(&option{
+		method:    "POST",
+		url:       "/wxa/getuserriskrank",
+		withToken: true,
+		body:      &copied,
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// GetUserEncryptKey 获取用户最近三次的加密key，用于解密wx.getUserCryptoManager加密的数据
+// 使用session_key对空字符串计算hmac_sha256作为用户态签名
+func (api *WechatAPI) GetUserEncryptKey(openid, sessionKey string) (*RespUserEncryptKey, *WechatResp, []error) {
+	mac := hmac.New(sha256.New, []byte(sessionKey))
+	mac.Write([]byte(""))
+
+	respData := &RespUserEncryptKey{}
+	resp, errs := api.Request(&option{
+		method:    "POST",
+		url:       "/wxa/business/getuserencryptkey",
+		withToken: true,
+		query: map[string]string{
+			"openid":     openid,
+			"signature":  hex.EncodeToString(mac.Sum(nil)),
+			"sig_method": "hmac_sha256",
+		},
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// Key 获取指定版本的加密key
+func (keys *RespUserEncryptKey) Key(version int) (*UserEncryptKey, error) {
+	for index := range keys.KeyInfoList {
+		if keys.KeyInfoList[index].Version == version {
+			return &keys.KeyInfoList[index], nil
+		}
+	}
+
+	return nil, fmt.Errorf("user encrypt key version %d not found", version)
+}
+
+// Decrypt 使用指定版本的加密key解密用户加密数据
+func (keys *RespUserEncryptKey) Decrypt(version int, encryptedData string) ([]byte, error) {
+	key, err := keys.Key(version)
+	if err != nil {
+		return nil, err
+	}
+
+	return crypto.DecryptData(key.EncryptKey, key.IV, encryptedData)
+}
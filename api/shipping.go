@@ -0,0 +1,237 @@
+package api
+
+const (
+	// ShippingOrderByOutTradeNo 使用商户号和商户订单号定位订单
+	ShippingOrderByOutTradeNo = 1
+	// ShippingOrderByTransactionID 使用微信支付单号定位订单
+	ShippingOrderByTransactionID = 2
+)
+
+const (
+	// LogisticsExpress 实体物流配送，采用快递公司进行实体物流配送形式
+	LogisticsExpress = 1
+	// LogisticsLocalDelivery 同城配送
+	LogisticsLocalDelivery = 2
+	// LogisticsVirtual 虚拟商品，如话费充值、点卡等，无实体配送形式
+	LogisticsVirtual = 3
+	// LogisticsSelfPickup 用户自提
+	LogisticsSelfPickup = 4
+)
+
+const (
+	// DeliveryUnified 统一发货
+	DeliveryUnified = 1
+	// DeliverySplit 分拆发货
+	DeliverySplit = 2
+)
+
+const (
+	// ShippingStateUnshipped 待发货
+	ShippingStateUnshipped = 1
+	// ShippingStateShipped 已发货
+	ShippingStateShipped = 2
+	// ShippingStateConfirmed 确认收货
+	ShippingStateConfirmed = 3
+	// ShippingStateCompleted 交易完成
+	ShippingStateCompleted = 4
+	// ShippingStateRefunded 已退款
+	ShippingStateRefunded = 5
+)
+
+type (
+	// ShippingOrderKey 订单，需要上传物流信息的订单
+	ShippingOrderKey struct {
+		OrderNumberType int    `json:"order_number_type"`        // 订单单号类型，见ShippingOrderBy*
+		TransactionID   string `json:"transaction_id,omitempty"` // 原支付交易对应的微信订单号
+		MchID           string `json:"mchid,omitempty"`          // 支付下单商户的商户号
+		OutTradeNo      string `json:"out_trade_no,omitempty"`   // 商户系统内部订单号
+	}
+
+	// ShippingContact 联系方式，顺丰快递必填，其他选填
+	ShippingContact struct {
+		ConsignorContact string `json:"consignor_contact,omitempty"` // 寄件人联系方式，采用掩码传输
+		ReceiverContact  string `json:"receiver_contact,omitempty"`  // 收件人联系方式，采用掩码传输
+	}
+
+	// ShippingItem 物流信息
+	ShippingItem struct {
+		TrackingNo     string           `json:"tracking_no,omitempty"`     // 物流单号，物流快递发货时必填
+		ExpressCompany string           `json:"express_company,omitempty"` // 物流公司编码，物流快递发货时必填
+		ItemDesc       string           `json:"item_desc"`                 // 商品信息，例如：微信红包抱枕*1个
+		Contact        *ShippingContact `json:"contact,omitempty"`         // 联系方式
+		UploadTime     string           `json:"upload_time,omitempty"`     // 查询订单时返回的上传时间
+	}
+
+	// ShippingPayer 支付者信息
+	ShippingPayer struct {
+		OpenID string `json:"openid"` // 用户标识，用户在小程序appid下的唯一标识
+	}
+
+	// ShippingInfo 发货信息录入参数
+	ShippingInfo struct {
+		OrderKey       *ShippingOrderKey `json:"order_key"`        // 订单
+		LogisticsType  int               `json:"logistics_type"`   // 物流模式，见Logistics*
+		DeliveryMode   int               `json:"delivery_mode"`    // 发货模式，见Delivery*
+		IsAllDelivered bool              `json:"is_all_delivered"` // 分拆发货模式时必填，是否已全部发货
+		ShippingList   []ShippingItem    `json:"shipping_list"`    // 物流信息列表，最多15个
+		UploadTime     string            `json:"upload_time"`      // 上传时间，RFC 3339格式
+		Payer          *ShippingPayer    `json:"payer"`            // 支付者信息
+	}
+
+	// ShippingSubOrder 合单子订单发货信息
+	ShippingSubOrder struct {
+		OrderKey       *ShippingOrderKey `json:"order_key"`
+		DeliveryMode   int               `json:"delivery_mode"`
+		IsAllDelivered bool              `json:"is_all_delivered"`
+		ShippingList   []ShippingItem    `json:"shipping_list"`
+	}
+
+	// CombinedShippingInfo 合单发货信息录入参数
+	CombinedShippingInfo struct {
+		OrderKey      *ShippingOrderKey  `json:"order_key"`      // 合单订单
+		SubOrders     []ShippingSubOrder `json:"sub_orders"`     // 子单物流详情
+		LogisticsType int                `json:"logistics_type"` // 物流模式，见Logistics*
+		UploadTime    string             `json:"upload_time"`    // 上传时间，RFC 3339格式
+		Payer         *ShippingPayer     `json:"payer"`          // 支付者信息
+	}
+
+	// ShippingOrderQuery 查询订单发货状态参数，使用微信支付单号或商户号+商户订单号
+	ShippingOrderQuery struct {
+		TransactionID   string `json:"transaction_id,omitempty"`    // 原支付交易对应的微信订单号
+		MerchantID      string `json:"merchant_id,omitempty"`       // 支付下单商户的商户号
+		SubMerchantID   string `json:"sub_merchant_id,omitempty"`   // 二级商户号
+		MerchantTradeNo string `json:"merchant_trade_no,omitempty"` // 商户系统内部订单号
+	}
+
+	// ShippingOrder 订单发货状态
+	ShippingOrder struct {
+		TransactionID   string               `json:"transaction_id"`    // 原支付交易对应的微信订单号
+		MerchantID      string               `json:"merchant_id"`       // 支付下单商户的商户号
+		SubMerchantID   string               `json:"sub_merchant_id"`   // 二级商户号
+		MerchantTradeNo string               `json:"merchant_trade_no"` // 商户系统内部订单号
+		Description     string               `json:"description"`       // 以分号连接的该支付单的所有商品描述
+		PaidAmount      int                  `json:"paid_amount"`       // 支付单实际支付金额，整型，单位：分
+		OpenID          string               `json:"openid"`            // 支付者openid
+		TradeCreateTime int64                `json:"trade_create_time"` // 交易创建时间，时间戳形式
+		PayTime         int64                `json:"pay_time"`          // 支付时间，时间戳形式
+		OrderState      int                  `json:"order_state"`       // 订单状态枚举，见ShippingState*
+		InComplaint     bool                 `json:"in_complaint"`      // 是否处在交易纠纷中
+		Shipping        *ShippingOrderDetail `json:"shipping"`          // 发货信息
+	}
+
+	// ShippingOrderDetail 订单的发货信息
+	ShippingOrderDetail struct {
+		DeliveryMode        int            `json:"delivery_mode"`         // 发货模式，见Delivery*
+		LogisticsType       int            `json:"logistics_type"`        // 物流模式，见Logistics*
+		FinishShipping      bool           `json:"finish_shipping"`       // 是否已完成全部发货
+		GoodsDesc           string         `json:"goods_desc"`            // 在小程序后台发货信息录入页录入的商品描述
+		FinishShippingCount int            `json:"finish_shipping_count"` // 已完成全部发货的次数，最多可以修改一次
+		ShippingList        []ShippingItem `json:"shipping_list"`         // 物流信息列表
+	}
+
+	// RespShippingOrder 查询订单发货状态结果
+	RespShippingOrder struct {
+		Order *ShippingOrder `json:"order"`
+	}
+
+	// ShippingTimeRange 支付时间范围
+	ShippingTimeRange struct {
+		BeginTime int64 `json:"begin_time,omitempty"` // 起始时间，时间戳形式
+		EndTime   int64 `json:"end_time,omitempty"`   // 结束时间，时间戳形式
+	}
+
+	// ShippingOrderListQuery 查询订单列表参数
+	ShippingOrderListQuery struct {
+		PayTimeRange *ShippingTimeRange `json:"pay_time_range,omitempty"` // 支付时间所属范围
+		OrderState   int                `json:"order_state,omitempty"`    // 订单状态枚举，见ShippingState*
+		OpenID       string             `json:"openid,omitempty"`         // 支付者openid
+		LastIndex    string             `json:"last_index,omitempty"`     // 翻页时使用，获取第一页时不用传入
+		PageSize     int                `json:"page_size,omitempty"`      // 翻页时使用，返回列表的长度，默认为100
+	}
+
+	// RespShippingOrderList 订单列表
+	RespShippingOrderList struct {
+		LastIndex string          `json:"last_index"` // 翻页时使用
+		HasMore   bool            `json:"has_more"`   // 是否还有更多支付单
+		OrderList []ShippingOrder `json:"order_list"` // 支付单信息列表
+	}
+
+	// ConfirmReceive 确认收货提醒参数
+	ConfirmReceive struct {
+		TransactionID   string `json:"transaction_id,omitempty"`    // 原支付交易对应的微信订单号
+		MerchantID      string `json:"merchant_id,omitempty"`       // 支付下单商户的商户号
+		SubMerchantID   string `json:"sub_merchant_id,omitempty"`   // 二级商户号
+		MerchantTradeNo string `json:"merchant_trade_no,omitempty"` // 商户系统内部订单号
+		ReceivedTime    int64  `json:"received_time"`               // 快递签收时间，时间戳形式
+	}
+)
+
+// UploadShippingInfo 发货信息录入
+func (api *WechatAPI) UploadShippingInfo(info *ShippingInfo) (*WechatResp, []error) {
+	return api.Request(&option{
+		method:    "POST",
+		url:       "/wxa/sec/order/upload_shipping_info",
+		withToken: true,
+		body:      info,
+	})
+}
+
+// UploadCombinedShippingInfo 发货信息合单录入
+func (api *WechatAPI) UploadCombinedShippingInfo(info *CombinedShippingInfo) (*WechatResp, []error) {
+	return api.Request(&option{
+		method:    "POST",
+		url:       "/wxa/sec/order/upload_combined_shipping_info",
+		withToken: true,
+		body:      info,
+	})
+}
+
+// GetShippingOrder 查询订单发货状态
+func (api *WechatAPI) GetShippingOrder(query *ShippingOrderQuery) (*RespShippingOrder, *WechatResp, []error) {
+	respData := &RespShippingOrder{}
+	resp, errs := api.Request(&option{
+		method:    "POST",
+		url:       "/wxa/sec/order/get_order",
+		withToken: true,
+		retryable: true,
+		body:      query,
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// GetShippingOrderList 查询订单列表，has_more为true时使用last_index继续翻页
+func (api *WechatAPI) GetShippingOrderList(query *ShippingOrderListQuery) (*RespShippingOrderList, *WechatResp, []error) {
+	respData := &RespShippingOrderList{}
+	resp, errs := api.Request(&option{
+		method:    "POST",
+		url:       "/wxa/sec/order/get_order_list",
+		withToken: true,
+		retryable: true,
+		body:      query,
+	}, respData)
+
+	return respData, resp, errs
+}
+
+// NotifyConfirmReceive 确认收货提醒接口，快递签收后可提醒用户确认收货，每个订单只能调用一次
+func (api *WechatAPI) NotifyConfirmReceive(confirm *ConfirmReceive) (*WechatResp, []error) {
+	return api.Request(&option{
+		method:    "POST",
+		url:       "/wxa/sec/order/notify_confirm_receive",
+		withToken: true,
+		body:      confirm,
+	})
+}
+
+// SetMsgJumpPath 消息跳转路径设置接口，用户点击发货消息时跳转到小程序的该页面
+func (api *WechatAPI) SetMsgJumpPath(path string) (*WechatResp, []error) {
+	return api.Request(&option{
+		method:    "POST",
+		url:       "/wxa/sec/order/set_msg_jump_path",
+		withToken: true,
+		body: map[string]string{
+			"path": path,
+		},
+	})
+}
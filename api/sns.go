@@ -11,7 +11,12 @@ type (
 )
 
 // Code2Session oauth code转换为unionId,openId,sessionKey
+// 请求失败或业务错误时不返回会话
 func (api *WechatAPI) Code2Session(code string) (*RespCode2Session, *WechatResp, []error) {
+	if api.component != nil {
+		return api.component.Code2Session(api.appID, code)
+	}
+
 	respData := &RespCode2Session{}
 	resp, errs := api.Request(&option{
 		method:    "GET",
@@ -23,7 +28,11 @@ func (api *WechatAPI) Code2Session(code string) (*RespCode2Session, *WechatResp,
 			"js_code":    code,
 			"grant_type": "authorization_code",
 		},
-	}, &respData)
+	}, respData)
+
+	if len(errs) != 0 || resp.ErrCode != 0 {
+		return nil, resp, errs
+	}
 
 	return respData, resp, errs
 }
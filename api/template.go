@@ -15,16 +15,18 @@ type (
 
 // GetTemplateList 获取帐号下已存在的模板列表
 func (api *WechatAPI) GetTemplateList(offset, count uint) (RespTemplateList, *WechatResp, []error) {
-	respData := RespTemplateList{}
+	respData := &struct {
+		List RespTemplateList `json:"list"`
+	}{}
 	resp, errs := api.Request(&option{
 		method:    "POST",
 		url:       "/cgi-bin/wxopen/template/list",
 		withToken: true,
-		query: map[string]uint{
+		body: map[string]uint{
 			"offset": offset,
 			"count":  count,
 		},
-	}, &respData)
+	}, respData)
 
-	return respData, resp, errs
+	return respData.List, resp, errs
 }
@@ -1,7 +1,7 @@
 package api
 
 import (
-	"log"
+	"sync"
 	"time"
 )
 
@@ -12,6 +12,14 @@ type (
 		Set(token string, exipresIn int, expireAt time.Time)
 	}
 
+	// MemoryTokenStore 基于内存的token存储器，仅适用于单实例部署
+	MemoryTokenStore struct {
+		token     string
+		expiresIn int
+		expireAt  time.Time
+		locker    *sync.RWMutex
+	}
+
 	// WechatRespToken 微信token响应结果
 	WechatRespToken struct {
 		Token     string `json:"access_token"`
@@ -19,21 +27,71 @@ type (
 	}
 )
 
+// NewMemoryTokenStore 新建一个内存token存储器
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{
+		locker: &sync.RWMutex{},
+	}
+}
+
+// Get 获取token
+func (store *MemoryTokenStore) Get() (string, int, time.Time) {
+	defer store.locker.RUnlock()
+	store.locker.RLock()
+
+	return store.token, store.expiresIn, store.expireAt
+}
+
+// Set 保存token
+func (store *MemoryTokenStore) Set(token string, expiresIn int, expireAt time.Time) {
+	defer store.locker.Unlock()
+	store.locker.Lock()
+
+	store.token, store.expiresIn, store.expireAt = token, expiresIn, expireAt
+}
+
 // GetToken 获取token
 // 优先 内存获取
 // 再次 store获取
 // 再次 生成并保存
 func (api *WechatAPI) GetToken() string {
 	token, exipresIn, expireAt := api.apiTokenStore.Get()
-	log.Printf("Applet.GetToken appID:%s exipresIn:%d expireAt:%s", api.appID, exipresIn, expireAt)
+	api.logger.Printf("Applet.GetToken appID:%s exipresIn:%d expireAt:%s", api.appID, exipresIn, expireAt)
 	return token
 }
 
+// GetTokenInfo 获取token及其有效期
+func (api *WechatAPI) GetTokenInfo() (token string, exipresIn int, expireAt time.Time) {
+	return api.apiTokenStore.Get()
+}
+
 // RenewToken 重新生成一个token
 func (api *WechatAPI) RenewToken() (*WechatResp, []error) {
 	defer api.locker.Unlock()
 	api.locker.Lock()
 
+	renew := api.renewer
+	if renew == nil {
+		renew = api.renewClientCredential
+	}
+
+	respToken, resp, errs := renew()
+
+	// 请求成功，解析内容
+	if len(errs) == 0 && resp != nil && resp.ErrCode == 0 {
+		apiToken := respToken.Token
+		apiTokenExpireIn := respToken.ExpiresIn
+		apiTokenExpireAt := time.Now().Add(time.Second * time.Duration(respToken.ExpiresIn-30)) // 提前30秒失效token
+
+		// 保存新的token
+		api.apiTokenStore.Set(apiToken, apiTokenExpireIn, apiTokenExpireAt)
+	}
+
+	return resp, errs
+}
+
+// renewClientCredential 使用小程序秘钥生成token
+func (api *WechatAPI) renewClientCredential() (*WechatRespToken, *WechatResp, []error) {
 	respToken := &WechatRespToken{}
 	resp, errs := api.Request(&option{
 		method:    "GET",
@@ -46,15 +104,5 @@ func (api *WechatAPI) RenewToken() (*WechatResp, []error) {
 		},
 	}, respToken)
 
-	// 请求成功，解析内容
-	if resp.ErrCode == 0 {
-		apiToken := respToken.Token
-		apiTokenExpireIn := respToken.ExpiresIn
-		apiTokenExpireAt := time.Now().Add(time.Second * time.Duration(respToken.ExpiresIn-30)) // 提前30秒失效token
-
-		// 保存新的token
-		api.apiTokenStore.Set(apiToken, apiTokenExpireIn, apiTokenExpireAt)
-	}
-
-	return resp, errs
+	return respToken, resp, errs
 }
@@ -0,0 +1,67 @@
+package api
+
+type (
+	// RespPaidUnionID 支付后获取UnionId响应结果
+	RespPaidUnionID struct {
+		UnionID string `json:"unionid"`
+	}
+
+	// RespPluginOpenPID 插件用户openpid响应结果
+	RespPluginOpenPID struct {
+		OpenPID string `json:"openpid"`
+	}
+)
+
+// GetPaidUnionID 用户支付完成后，通过微信支付订单号获取UnionId，无需用户授权
+// 失败时respData为nil，与Code2Session一致
+func (api *WechatAPI) GetPaidUnionID(openid, transactionID string) (*RespPaidUnionID, *WechatResp, []error) {
+	return api.getPaidUnionID(map[string]string{
+		"openid":         openid,
+		"transaction_id": transactionID,
+	})
+}
+
+// GetPaidUnionIDByOrder 用户支付完成后，通过商户号和商户订单号获取UnionId
+func (api *WechatAPI) GetPaidUnionIDByOrder(openid, mchID, outTradeNo string) (*RespPaidUnionID, *WechatResp, []error) {
+	return api.getPaidUnionID(map[string]string{
+		"openid":       openid,
+		"mch_id":       mchID,
+		"out_trade_no": outTradeNo,
+	})
+}
+
+// GetPluginOpenPID 通过插件中wx.pluginLogin获取的code，获取插件用户的openpid
+// 失败时respData为nil
+func (api *WechatAPI) GetPluginOpenPID(code string) (*RespPluginOpenPID, *WechatResp, []error) {
+	respData := &RespPluginOpenPID{}
+	resp, errs := api.Request(&option{
+		method:    "POST",
+		url:       "/wxa/getpluginopenpid",
+		withToken: true,
+		body: map[string]string{
+			"code": code,
+		},
+	}, respData)
+
+	if len(errs) != 0 || resp.ErrCode != 0 {
+		return nil, resp, errs
+	}
+
+	return respData, resp, errs
+}
+
+func (api *WechatAPI) getPaidUnionID(query map[string]string) (*RespPaidUnionID, *WechatResp, []error) {
+	respData := &RespPaidUnionID{}
+	resp, errs := api.Request(&option{
+		method:    "GET",
+		url:       "/wxa/getpaidunionid",
+		withToken: true,
+		query:     query,
+	}, respData)
+
+	if len(errs) != 0 || resp.ErrCode != 0 {
+		return nil, resp, errs
+	}
+
+	return respData, resp, errs
+}
@@ -0,0 +1,26 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/amazing-gao/applet/applettest"
+)
+
+func TestGetPaidUnionID(t *testing.T) {
+	wechatAPI, srv := newTestAPI(t)
+	defer srv.Close()
+	srv.Handle("/wxa/getpaidunionid", true, func(call *applettest.Call) (int, interface{}) {
+		if call.Query.Get("transaction_id") == "" {
+			return http.StatusOK, map[string]interface{}{"errcode": 89300, "errmsg": "订单无效"}
+		}
+		return http.StatusOK, map[string]interface{}{"errcode": 0, "unionid": "unionid"}
+	})
+
+	if respData, resp, errs := wechatAPI.GetPaidUnionID("openid", "4200000001"); len(errs) != 0 || resp.ErrCode != 0 || respData.UnionID != "unionid" {
+		t.Fatalf("paid unionid got %+v %+v %v", respData, resp, errs)
+	}
+	if respData, resp, errs := wechatAPI.GetPaidUnionIDByOrder("openid", "1900000001", "order-1"); len(errs) != 0 || resp.ErrCode != 89300 || respData != nil {
+		t.Fatalf("failed paid unionid got %+v %+v %v", respData, resp, errs)
+	}
+}
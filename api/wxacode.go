@@ -0,0 +1,32 @@
+package api
+
+type (
+	// WxaCodeUnlimited 获取不限制数量的小程序码参数
+	WxaCodeUnlimited struct {
+		Scene      string        `json:"scene"`                 // 场景值，最大32个可见字符
+		Page       string        `json:"page,omitempty"`        // 已发布小程序的页面，默认主页
+		CheckPath  *bool         `json:"check_path,omitempty"`  // 检查page是否存在，默认true
+		EnvVersion string        `json:"env_version,omitempty"` // release trial develop
+		Width      int           `json:"width,omitempty"`       // 二维码的宽度，单位px，最小280，最大1280
+		AutoColor  bool          `json:"auto_color,omitempty"`  // 自动配置线条颜色
+		LineColor  *WxaCodeColor `json:"line_color,omitempty"`  // auto_color为false时生效
+		IsHyaline  bool          `json:"is_hyaline,omitempty"`  // 是否需要透明底色
+	}
+
+	// WxaCodeColor 小程序码线条颜色
+	WxaCodeColor struct {
+		R int `json:"r"`
+		G int `json:"g"`
+		B int `json:"b"`
+	}
+)
+
+// GetUnlimitedWxaCode 获取不限制数量的小程序码，返回图片内容
+func (api *WechatAPI) GetUnlimitedWxaCode(code *WxaCodeUnlimited) ([]byte, *WechatResp, []error) {
+	return api.RequestRaw(&option{
+		method:    "POST",
+		url:       "/wxa/getwxacodeunlimit",
+		withToken: true,
+		body:      code,
+	})
+}
@@ -1,9 +1,12 @@
 package applet
 
 import (
+	"errors"
+
 	"github.com/amazing-gao/applet/api"
 	"github.com/amazing-gao/applet/crypto"
 	"github.com/amazing-gao/applet/message"
+	"github.com/amazing-gao/applet/pay"
 )
 
 type (
@@ -16,22 +19,55 @@ type (
 		API            *api.WechatAPI           // 小程序接口
 		Crypto         *crypto.WechatCrypto     // 微信加密解密工具
 		Messager       *message.WechatMessenger // 小程序消息信使
+		Pay            *pay.Client              // 微信支付，未设置WithPay时为nil
 	}
 )
 
 // NewApplet 新建一个小程序实例
-func NewApplet(appID, appKey, appToken, encodingAESKey string, tokenStore api.WechatTokenStore) *Applet {
-	apix := api.NewWechatAPI(appID, appKey, tokenStore)
-	crypto := crypto.NewWechatCrypto(appID, appToken, encodingAESKey)
+// examples:
+// app, err := applet.NewApplet("your appid", applet.WithSecret("your secret"), applet.WithTokenStore(store))
+// app, err := applet.NewApplet("your appid", applet.WithToken("your token"), applet.WithEncodingAESKey("your aes key"))
+func NewApplet(appID string, opts ...Option) (*Applet, error) {
+	s := &settings{}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	apix, err := api.NewWechatAPI(appID, s.apiOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	crypto := crypto.NewWechatCrypto(appID, s.appToken, s.encodingAESKey)
 	messager := message.NewWechatMessager(crypto)
+	// 未设置消息加密秘钥时只能接受明文消息
+	if s.encodingAESKey == "" {
+		messager.SetSecurityMode(message.ModePlaintext)
+	}
+	for _, configure := range s.messengerSettings {
+		configure(messager)
+	}
+	if s.encodingAESKey == "" && messager.SecurityMode() != message.ModePlaintext {
+		return nil, errors.New("encodingAESKey is required unless the messenger is in plaintext mode")
+	}
+
+	var payClient *pay.Client
+	if s.mchID != "" {
+		if payClient, err = pay.NewClient(appID, s.mchID, s.payOptions...); err != nil {
+			return nil, err
+		}
+	}
 
 	return &Applet{
 		appID:          appID,
-		appKey:         appKey,
-		appToken:       appToken,
-		encodingAESKey: encodingAESKey,
+		appKey:         s.appKey,
+		appToken:       s.appToken,
+		encodingAESKey: s.encodingAESKey,
 		API:            apix,
 		Crypto:         crypto,
 		Messager:       messager,
-	}
+		Pay:            payClient,
+	}, nil
 }
@@ -0,0 +1,217 @@
+package applettest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/amazing-gao/applet/crypto"
+	"github.com/amazing-gao/applet/message"
+)
+
+type (
+	// Pusher 模拟微信服务器构造消息推送请求，并校验、解密信使的被动回复
+	// examples:
+	// pusher := applettest.NewPusher("your appid", "your token", "your aes key").SetSecure(true)
+	// rec, _ := pusher.Push(app.Messager, applettest.NewTextMessage("gh_xxx", "openid", "hello"))
+	// reply, _ := pusher.DecodeReply(rec)
+	Pusher struct {
+		crypto *crypto.WechatCrypto
+		secure bool
+		format message.Format
+	}
+
+	// envelope 安全模式下的推送消息
+	envelope struct {
+		XMLName    xml.Name `json:"-" xml:"xml"`
+		ToUserName string   `json:"ToUserName" xml:"ToUserName"`
+		Encrypt    string   `json:"Encrypt" xml:"Encrypt"`
+	}
+)
+
+// NewPusher 新建一个消息推送模拟器，默认明文模式、XML格式
+func NewPusher(appID, appToken, encodingAESKey string) *Pusher {
+	return &Pusher{
+		crypto: crypto.NewWechatCrypto(appID, appToken, encodingAESKey),
+		format: message.FormatXML,
+	}
+}
+
+// SetSecure 设置是否使用安全模式推送
+func (pusher *Pusher) SetSecure(secure bool) *Pusher {
+	pusher.secure = secure
+
+	return pusher
+}
+
+// SetFormat 设置推送的数据格式
+func (pusher *Pusher) SetFormat(format message.Format) *Pusher {
+	pusher.format = format
+
+	return pusher
+}
+
+// NewVerifyRequest 构造服务器地址校验请求
+func (pusher *Pusher) NewVerifyRequest(echostr string) *http.Request {
+	querys := pusher.sign("")
+	querys.Set("echostr", echostr)
+
+	return httptest.NewRequest(http.MethodGet, "/?"+querys.Encode(), nil)
+}
+
+// NewRequest 构造消息推送请求，安全模式下加密消息并计算msg_signature
+func (pusher *Pusher) NewRequest(msg *message.Message) (*http.Request, error) {
+	rawMsg, err := pusher.marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptMsg := ""
+	if pusher.secure {
+		if err := pusher.crypto.CheckKey(); err != nil {
+			return nil, err
+		}
+
+		encryptMsg = pusher.crypto.Encrypt(string(rawMsg))
+		rawMsg, err = pusher.marshal(&envelope{ToUserName: msg.ToUserName, Encrypt: encryptMsg})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	querys := pusher.sign(encryptMsg)
+	querys.Set("openid", msg.FromUserName)
+
+	request := httptest.NewRequest(http.MethodPost, "/?"+querys.Encode(), bytes.NewReader(rawMsg))
+	request.Header.Set("Content-Type", pusher.format.ContentType())
+
+	return request, nil
+}
+
+// Push 构造消息推送请求并交给handler处理，返回响应记录
+func (pusher *Pusher) Push(handler http.Handler, msg *message.Message) (*httptest.ResponseRecorder, error) {
+	request, err := pusher.NewRequest(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder, nil
+}
+
+// DecodeReply 解析信使的被动回复，安全模式下先校验MsgSignature再解密
+// 响应为success或空时返回nil
+func (pusher *Pusher) DecodeReply(recorder *httptest.ResponseRecorder) (*message.Reply, error) {
+	if recorder.Code != http.StatusOK {
+		return nil, errors.New(recorder.Body.String())
+	}
+
+	rawReply := recorder.Body.Bytes()
+	if len(rawReply) == 0 || string(rawReply) == "success" {
+		return nil, nil
+	}
+
+	if pusher.secure {
+		encryptReply := &message.EncryptReply{}
+		if err := pusher.unmarshal(rawReply, encryptReply); err != nil {
+			return nil, err
+		}
+
+		timestamp := strconv.FormatInt(encryptReply.TimeStamp, 10)
+		if pusher.crypto.CalcMsgSignature(timestamp, encryptReply.Nonce, encryptReply.Encrypt) != encryptReply.MsgSignature {
+			return nil, errors.New("invalid reply signature")
+		}
+		plaintext, err := pusher.crypto.Decrypt(encryptReply.Encrypt)
+		if err != nil {
+			return nil, err
+		}
+		rawReply = []byte(plaintext)
+	}
+
+	reply := &message.Reply{}
+	if err := pusher.unmarshal(rawReply, reply); err != nil {
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+// sign 生成时间戳、随机数及签名参数
+func (pusher *Pusher) sign(encryptMsg string) url.Values {
+	nonceBytes := make([]byte, 8)
+	rand.Read(nonceBytes)
+	nonce := hex.EncodeToString(nonceBytes)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	querys := url.Values{}
+	querys.Set("timestamp", timestamp)
+	querys.Set("nonce", nonce)
+	querys.Set("signature", pusher.crypto.CalcSignature(timestamp, nonce))
+	if pusher.secure && encryptMsg != "" {
+		querys.Set("encrypt_type", "aes")
+		querys.Set("msg_signature", pusher.crypto.CalcMsgSignature(timestamp, nonce, encryptMsg))
+	}
+
+	return querys
+}
+
+func (pusher *Pusher) marshal(v interface{}) ([]byte, error) {
+	if pusher.format == message.FormatJSON {
+		return json.Marshal(v)
+	}
+
+	return xml.Marshal(v)
+}
+
+func (pusher *Pusher) unmarshal(raw []byte, v interface{}) error {
+	if pusher.format == message.FormatJSON {
+		return json.Unmarshal(raw, v)
+	}
+
+	return xml.Unmarshal(raw, v)
+}
+
+// NewTextMessage 构造文本消息
+func NewTextMessage(toUserName, fromUserName, content string) *message.Message {
+	return &message.Message{
+		MsgID:        int(time.Now().UnixNano() / int64(time.Microsecond)),
+		MsgType:      "text",
+		ToUserName:   toUserName,
+		FromUserName: fromUserName,
+		CreateTime:   int(time.Now().Unix()),
+		Content:      content,
+	}
+}
+
+// NewImageMessage 构造图片消息
+func NewImageMessage(toUserName, fromUserName, mediaID string) *message.Message {
+	return &message.Message{
+		MsgID:        int(time.Now().UnixNano() / int64(time.Microsecond)),
+		MsgType:      "image",
+		ToUserName:   toUserName,
+		FromUserName: fromUserName,
+		CreateTime:   int(time.Now().Unix()),
+		MediaID:      mediaID,
+	}
+}
+
+// NewEventMessage 构造事件推送，例如user_enter_tempsession
+func NewEventMessage(toUserName, fromUserName, event string) *message.Message {
+	return &message.Message{
+		MsgType:      "event",
+		ToUserName:   toUserName,
+		FromUserName: fromUserName,
+		CreateTime:   int(time.Now().Unix()),
+		Event:        event,
+	}
+}
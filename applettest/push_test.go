@@ -0,0 +1,66 @@
+package applettest_test
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"testing"
+
+	"github.com/amazing-gao/applet/applettest"
+	"github.com/amazing-gao/applet/crypto"
+	"github.com/amazing-gao/applet/message"
+)
+
+const (
+	testAppID  = "wx0000000000000000"
+	testToken  = "token"
+	testAESKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+)
+
+func newMessenger() *message.WechatMessenger {
+	return message.NewWechatMessager(crypto.NewWechatCrypto(testAppID, testToken, testAESKey)).
+		SetLogger(log.New(ioutil.Discard, "", 0))
+}
+
+func TestPusherSecureRoundTrip(t *testing.T) {
+	for _, format := range []message.Format{message.FormatXML, message.FormatJSON} {
+		pusher := applettest.NewPusher(testAppID, testToken, testAESKey).SetSecure(true).SetFormat(format)
+		received := ""
+		mgr := newMessenger().RegisterReplyHandler(func(msg *message.Message, err error) *message.Reply {
+			received = msg.Content
+			return message.NewTransferCustomerServiceReply(msg)
+		})
+
+		rec, err := pusher.Push(mgr, applettest.NewTextMessage("gh_test", "openid", "hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reply, err := pusher.DecodeReply(rec)
+		if err != nil || received != "hello" || reply.MsgType != message.ReplyTransferCustomerService || reply.ToUserName != "openid" {
+			t.Fatalf("%v round trip got %d %v %+v", format, rec.Code, err, reply)
+		}
+	}
+}
+
+func TestPusherDecodeReply(t *testing.T) {
+	pusher := applettest.NewPusher(testAppID, testToken, testAESKey).SetSecure(true)
+	mgr := newMessenger()
+
+	rec, _ := pusher.Push(mgr, applettest.NewTextMessage("gh_test", "openid", "hello"))
+	if reply, err := pusher.DecodeReply(rec); err != nil || reply != nil {
+		t.Fatalf("success reply got %+v %v", reply, err)
+	}
+
+	// 其他秘钥加密的回复无法通过校验
+	other := applettest.NewPusher(testAppID, "other", testAESKey).SetSecure(true)
+	mgr = mgr.RegisterReplyHandler(func(msg *message.Message, err error) *message.Reply {
+		return message.NewTransferCustomerServiceReply(msg)
+	})
+	rec, _ = pusher.Push(mgr, applettest.NewTextMessage("gh_test", "openid", "hello"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("push got %d", rec.Code)
+	}
+	if _, err := other.DecodeReply(rec); err == nil {
+		t.Fatal("reply signed with another token should be rejected")
+	}
+}
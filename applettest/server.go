@@ -0,0 +1,347 @@
+// Package applettest 提供用于集成测试的微信接口模拟服务器
+package applettest
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/amazing-gao/applet/api"
+)
+
+const (
+	// TokenExpiresIn 模拟服务器签发的access_token有效期
+	TokenExpiresIn = 7200
+)
+
+type (
+	// Server 进程内的微信接口模拟服务器
+	// examples:
+	// srv := applettest.NewServer("your appid", "your secret")
+	// defer srv.Close()
+	// app, _ := applet.NewApplet("your appid", applet.WithSecret("your secret"), applet.WithBaseURL(srv.URL))
+	Server struct {
+		*httptest.Server
+		appID     string
+		appSecret string
+		handlers  map[string]handler               // 接口路径 -> 模拟接口
+		tokens    map[string]time.Time             // access_token -> 过期时间
+		sessions  map[string]*api.RespCode2Session // js_code -> 会话
+		scripts   map[string][]Script              // 接口路径 -> 预设的响应
+		latency   map[string]time.Duration         // 接口路径 -> 响应延迟
+		calls     []*Call
+		tokenSeq  int
+		locker    *sync.Mutex
+	}
+
+	// Call 接口调用记录
+	Call struct {
+		Method string
+		Path   string
+		Query  url.Values
+		Body   []byte
+		Time   time.Time
+	}
+
+	// Script 预设的接口响应，按顺序各生效一次
+	Script struct {
+		Status  int           // http状态码，默认200
+		ErrCode int           // 业务错误码
+		ErrMsg  string        // 业务错误信息
+		Body    interface{}   // 自定义的响应内容，优先于ErrCode
+		Latency time.Duration // 响应延迟
+	}
+
+	// handler 模拟接口处理器
+	handler func(call *Call) (status int, body interface{})
+)
+
+// NewServer 新建并启动一个模拟服务器
+func NewServer(appID, appSecret string) *Server {
+	srv := &Server{
+		appID:     appID,
+		appSecret: appSecret,
+		handlers:  map[string]handler{},
+		tokens:    map[string]time.Time{},
+		sessions:  map[string]*api.RespCode2Session{},
+		scripts:   map[string][]Script{},
+		latency:   map[string]time.Duration{},
+		locker:    &sync.Mutex{},
+	}
+
+	srv.handle("/cgi-bin/token", srv.token)
+	srv.handle("/sns/jscode2session", srv.code2Session)
+	srv.handle("/cgi-bin/message/custom/send", srv.withToken(srv.ok))
+	srv.handle("/cgi-bin/message/custom/typing", srv.withToken(srv.ok))
+	srv.handle("/cgi-bin/message/subscribe/send", srv.withToken(srv.ok))
+	srv.handle("/cgi-bin/message/wxopen/template/uniform_send", srv.withToken(srv.ok))
+	srv.handle("/cgi-bin/wxopen/template/list", srv.withToken(srv.templateList))
+	srv.handle("/wxa/setdynamicdata", srv.withToken(srv.ok))
+	srv.handle("/wxa/getwxacodeunlimit", srv.withToken(srv.wxacode))
+	srv.handle("/wxa/generatescheme", srv.withToken(srv.scheme))
+
+	srv.Server = httptest.NewServer(srv.wrap(srv.route))
+
+	return srv
+}
+
+// Handle 注册或覆盖一个模拟接口，body会被序列化为json，[]byte原样返回
+func (srv *Server) Handle(path string, requireToken bool, fn func(call *Call) (status int, body interface{})) {
+	if requireToken {
+		srv.handle(path, srv.withToken(fn))
+	} else {
+		srv.handle(path, fn)
+	}
+}
+
+// Script 预设接口接下来的响应，例如模拟errcode、5xx及超时
+func (srv *Server) Script(path string, scripts ...Script) {
+	defer srv.locker.Unlock()
+	srv.locker.Lock()
+
+	srv.scripts[path] = append(srv.scripts[path], scripts...)
+}
+
+// SetLatency 设置接口的响应延迟，path为空时对全部接口生效
+func (srv *Server) SetLatency(path string, latency time.Duration) {
+	defer srv.locker.Unlock()
+	srv.locker.Lock()
+
+	srv.latency[path] = latency
+}
+
+// AddSession 预设js_code对应的会话，未预设的js_code会自动生成会话
+func (srv *Server) AddSession(code string, session *api.RespCode2Session) {
+	defer srv.locker.Unlock()
+	srv.locker.Lock()
+
+	srv.sessions[code] = session
+}
+
+// IssueToken 签发一个有效的access_token，可直接写入token存储器
+func (srv *Server) IssueToken() string {
+	defer srv.locker.Unlock()
+	srv.locker.Lock()
+
+	return srv.issueToken()
+}
+
+// ExpireTokens 使已签发的access_token全部过期
+func (srv *Server) ExpireTokens() {
+	defer srv.locker.Unlock()
+	srv.locker.Lock()
+
+	for token := range srv.tokens {
+		srv.tokens[token] = time.Now()
+	}
+}
+
+// Calls 获取全部调用记录，path不为空时只返回该接口的调用记录
+func (srv *Server) Calls(path string) []*Call {
+	defer srv.locker.Unlock()
+	srv.locker.Lock()
+
+	calls := []*Call{}
+	for _, call := range srv.calls {
+		if path == "" || call.Path == path {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+// Reset 清空调用记录、预设响应和延迟，已签发的access_token仍然有效
+func (srv *Server) Reset() {
+	defer srv.locker.Unlock()
+	srv.locker.Lock()
+
+	srv.calls = nil
+	srv.scripts = map[string][]Script{}
+	srv.latency = map[string]time.Duration{}
+}
+
+// Unmarshal 将调用的请求体解析到v
+func (call *Call) Unmarshal(v interface{}) error {
+	return json.Unmarshal(call.Body, v)
+}
+
+func (srv *Server) handle(path string, fn handler) {
+	defer srv.locker.Unlock()
+	srv.locker.Lock()
+
+	srv.handlers[path] = fn
+}
+
+// route 按路径分发到模拟接口
+func (srv *Server) route(call *Call) (int, interface{}) {
+	srv.locker.Lock()
+	fn, ok := srv.handlers[call.Path]
+	srv.locker.Unlock()
+
+	if !ok {
+		return http.StatusNotFound, errResp(40066, "invalid url")
+	}
+
+	return fn(call)
+}
+
+// wrap 记录调用，应用延迟和预设响应，再交给模拟接口处理
+func (srv *Server) wrap(fn handler) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		rawBody, _ := ioutil.ReadAll(request.Body)
+		call := &Call{
+			Method: request.Method,
+			Path:   request.URL.Path,
+			Query:  request.URL.Query(),
+			Body:   rawBody,
+			Time:   time.Now(),
+		}
+
+		srv.locker.Lock()
+		srv.calls = append(srv.calls, call)
+		latency, ok := srv.latency[call.Path]
+		if !ok {
+			latency = srv.latency[""]
+		}
+		var script *Script
+		if scripts := srv.scripts[call.Path]; len(scripts) != 0 {
+			script = &scripts[0]
+			srv.scripts[call.Path] = scripts[1:]
+		}
+		srv.locker.Unlock()
+
+		status, body := 0, interface{}(nil)
+		if script != nil {
+			latency += script.Latency
+			status, body = script.Status, script.Body
+			if body == nil {
+				body = errResp(script.ErrCode, script.ErrMsg)
+			}
+		} else {
+			status, body = fn(call)
+		}
+
+		time.Sleep(latency)
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		if raw, ok := body.([]byte); ok {
+			writer.Header().Set("Content-Type", "image/png")
+			writer.WriteHeader(status)
+			writer.Write(raw)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		writer.WriteHeader(status)
+		json.NewEncoder(writer).Encode(body)
+	}
+}
+
+// withToken 校验access_token
+func (srv *Server) withToken(fn handler) handler {
+	return func(call *Call) (int, interface{}) {
+		srv.locker.Lock()
+		expireAt, ok := srv.tokens[call.Query.Get("access_token")]
+		srv.locker.Unlock()
+
+		if !ok {
+			return http.StatusOK, errResp(40001, "invalid credential, access_token is invalid or not latest")
+		} else if time.Now().After(expireAt) {
+			return http.StatusOK, errResp(42001, "access_token expired")
+		}
+
+		return fn(call)
+	}
+}
+
+func (srv *Server) token(call *Call) (int, interface{}) {
+	if call.Query.Get("grant_type") != "client_credential" {
+		return http.StatusOK, errResp(40002, "invalid grant_type")
+	} else if call.Query.Get("appid") != srv.appID {
+		return http.StatusOK, errResp(40013, "invalid appid")
+	} else if call.Query.Get("secret") != srv.appSecret {
+		return http.StatusOK, errResp(40125, "invalid appsecret")
+	}
+
+	return http.StatusOK, &api.WechatRespToken{
+		Token:     srv.IssueToken(),
+		ExpiresIn: TokenExpiresIn,
+	}
+}
+
+func (srv *Server) code2Session(call *Call) (int, interface{}) {
+	code := call.Query.Get("js_code")
+	if call.Query.Get("appid") != srv.appID {
+		return http.StatusOK, errResp(40013, "invalid appid")
+	} else if call.Query.Get("secret") != srv.appSecret {
+		return http.StatusOK, errResp(40125, "invalid appsecret")
+	} else if code == "" {
+		return http.StatusOK, errResp(40029, "invalid code")
+	}
+
+	defer srv.locker.Unlock()
+	srv.locker.Lock()
+
+	session, ok := srv.sessions[code]
+	if !ok {
+		sessionKey := make([]byte, 16)
+		rand.Read(sessionKey)
+		session = &api.RespCode2Session{
+			OpenID:     "openid_" + code,
+			SessionKey: base64.StdEncoding.EncodeToString(sessionKey),
+		}
+		srv.sessions[code] = session
+	}
+
+	return http.StatusOK, session
+}
+
+func (srv *Server) templateList(call *Call) (int, interface{}) {
+	return http.StatusOK, map[string]interface{}{
+		"errcode": 0,
+		"errmsg":  "ok",
+		"list":    []api.RespTemplate{},
+	}
+}
+
+func (srv *Server) wxacode(call *Call) (int, interface{}) {
+	return http.StatusOK, pngPixel
+}
+
+func (srv *Server) scheme(call *Call) (int, interface{}) {
+	return http.StatusOK, map[string]interface{}{
+		"errcode":  0,
+		"errmsg":   "ok",
+		"openlink": fmt.Sprintf("weixin://dl/business/?t=%s", call.Time.Format("150405")),
+	}
+}
+
+func (srv *Server) ok(call *Call) (int, interface{}) {
+	return http.StatusOK, errResp(0, "ok")
+}
+
+// issueToken 签发access_token，调用方需持有锁
+func (srv *Server) issueToken() string {
+	srv.tokenSeq++
+	token := fmt.Sprintf("ACCESS_TOKEN_%s_%d", srv.appID, srv.tokenSeq)
+	srv.tokens[token] = time.Now().Add(TokenExpiresIn * time.Second)
+
+	return token
+}
+
+func errResp(errCode int, errMsg string) *api.WechatResp {
+	return &api.WechatResp{ErrCode: errCode, ErrMsg: errMsg}
+}
+
+// pngPixel 1x1的透明png图片
+var pngPixel, _ = base64.StdEncoding.DecodeString("iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNkYPhfDwAChwGA60e6kgAAAABJRU5ErkJggg==")
@@ -0,0 +1,116 @@
+package applettest_test
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/amazing-gao/applet/api"
+	"github.com/amazing-gao/applet/applettest"
+)
+
+func newAPI(t *testing.T, srv *applettest.Server, opts ...api.Option) *api.WechatAPI {
+	opts = append([]api.Option{
+		api.WithAppKey("secret"),
+		api.WithBaseURL(srv.URL),
+		api.WithRetry(0, 0),
+		api.WithLogger(log.New(ioutil.Discard, "", 0)),
+	}, opts...)
+
+	wechatAPI, err := api.NewWechatAPI("wx0000000000000000", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, errs := wechatAPI.RenewToken(); len(errs) != 0 || resp.ErrCode != 0 {
+		t.Fatalf("renew token got %+v %v", resp, errs)
+	}
+
+	return wechatAPI
+}
+
+func TestServerScript(t *testing.T) {
+	srv := applettest.NewServer("wx0000000000000000", "secret")
+	defer srv.Close()
+	wechatAPI := newAPI(t, srv)
+
+	srv.Script("/cgi-bin/message/custom/typing",
+		applettest.Script{ErrCode: 45047, ErrMsg: "out of response count limit"},
+		applettest.Script{Status: http.StatusInternalServerError})
+
+	if resp, errs := wechatAPI.SetTyping("openid", "Typing"); len(errs) != 0 || resp.ErrCode != 45047 {
+		t.Fatalf("scripted errcode got %+v %v", resp, errs)
+	}
+	if _, errs := wechatAPI.SetTyping("openid", "Typing"); len(errs) == 0 {
+		t.Fatal("scripted 5xx should fail")
+	}
+	// 预设响应用完后恢复默认的模拟接口
+	if resp, errs := wechatAPI.SetTyping("openid", "Typing"); len(errs) != 0 || resp.ErrCode != 0 {
+		t.Fatalf("default response got %+v %v", resp, errs)
+	}
+
+	if calls := srv.Calls("/cgi-bin/message/custom/typing"); len(calls) != 3 {
+		t.Fatalf("recorded %d calls", len(calls))
+	}
+	srv.Reset()
+	if calls := srv.Calls(""); len(calls) != 0 {
+		t.Fatalf("recorded %d calls after reset", len(calls))
+	}
+}
+
+func TestServerLatency(t *testing.T) {
+	srv := applettest.NewServer("wx0000000000000000", "secret")
+	defer srv.Close()
+	wechatAPI := newAPI(t, srv, api.WithHTTPClient(&http.Client{Timeout: 100 * time.Millisecond}))
+
+	srv.SetLatency("/cgi-bin/message/custom/typing", 20*time.Millisecond)
+	start := time.Now()
+	if _, errs := wechatAPI.SetTyping("openid", "Typing"); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("latency was not applied, took %v", elapsed)
+	}
+
+	// 预设响应的延迟叠加在接口延迟上，用于模拟超时
+	srv.Script("/cgi-bin/message/custom/typing", applettest.Script{Latency: 200 * time.Millisecond})
+	if _, errs := wechatAPI.SetTyping("openid", "Typing"); len(errs) == 0 {
+		t.Fatal("scripted latency should time out")
+	}
+}
+
+func TestServerTokenExpiry(t *testing.T) {
+	srv := applettest.NewServer("wx0000000000000000", "secret")
+	defer srv.Close()
+	wechatAPI := newAPI(t, srv)
+
+	srv.ExpireTokens()
+	if resp, errs := wechatAPI.SetTyping("openid", "Typing"); len(errs) != 0 || resp.ErrCode != 42001 {
+		t.Fatalf("expired token got %+v %v", resp, errs)
+	}
+
+	if resp, errs := wechatAPI.RenewToken(); len(errs) != 0 || resp.ErrCode != 0 {
+		t.Fatalf("renew token got %+v %v", resp, errs)
+	}
+	if resp, errs := wechatAPI.SetTyping("openid", "Typing"); len(errs) != 0 || resp.ErrCode != 0 {
+		t.Fatalf("renewed token got %+v %v", resp, errs)
+	}
+}
+
+func TestServerTemplateList(t *testing.T) {
+	srv := applettest.NewServer("wx0000000000000000", "secret")
+	defer srv.Close()
+	wechatAPI := newAPI(t, srv)
+
+	if _, resp, errs := wechatAPI.GetTemplateList(10, 20); len(errs) != 0 || resp.ErrCode != 0 {
+		t.Fatalf("template list got %+v %v", resp, errs)
+	}
+
+	// 分页参数在请求体中
+	body := map[string]uint{}
+	calls := srv.Calls("/cgi-bin/wxopen/template/list")
+	if len(calls) != 1 || calls[0].Unmarshal(&body) != nil || body["offset"] != 10 || body["count"] != 20 {
+		t.Fatalf("template list body got %+v", body)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"github.com/amazing-gao/applet"
+	"github.com/amazing-gao/applet/api"
+	"github.com/amazing-gao/applet/message"
+)
+
+func session(app *applet.Applet, args []string) (interface{}, error) {
+	if err := requireArgs(args, 1, "<code>"); err != nil {
+		return nil, err
+	}
+
+	return result(app.API.Code2Session(args[0]))
+}
+
+func sendText(app *applet.Applet, args []string) (interface{}, error) {
+	if err := requireArgs(args, 2, "<openid> <content>"); err != nil {
+		return nil, err
+	}
+	if err := ensureToken(app); err != nil {
+		return nil, err
+	}
+
+	resp, errs := app.API.SendText(args[0], args[1])
+	return result(nil, resp, errs)
+}
+
+func sendImage(app *applet.Applet, args []string) (interface{}, error) {
+	if err := requireArgs(args, 2, "<openid> <media_id>"); err != nil {
+		return nil, err
+	}
+	if err := ensureToken(app); err != nil {
+		return nil, err
+	}
+
+	resp, errs := app.API.SendImage(args[0], args[1])
+	return result(nil, resp, errs)
+}
+
+func sendLink(app *applet.Applet, args []string) (interface{}, error) {
+	link := &api.CustomerMsgLink{}
+	flags := flag.NewFlagSet("send link", flag.ExitOnError)
+	flags.StringVar(&link.Title, "title", "", "标题")
+	flags.StringVar(&link.Description, "description", "", "描述")
+	flags.StringVar(&link.URL, "url", "", "跳转链接")
+	flags.StringVar(&link.ThumbURL, "thumb-url", "", "图片链接")
+	if err := requireArgs(args, 1, "<openid>"); err != nil {
+		return nil, err
+	}
+	flags.Parse(args[1:])
+	if err := ensureToken(app); err != nil {
+		return nil, err
+	}
+
+	resp, errs := app.API.SendLink(args[0], link)
+	return result(nil, resp, errs)
+}
+
+func sendApplet(app *applet.Applet, args []string) (interface{}, error) {
+	card := &api.CustomerMsgApplet{}
+	flags := flag.NewFlagSet("send applet", flag.ExitOnError)
+	flags.StringVar(&card.Title, "title", "", "标题")
+	flags.StringVar(&card.PagePath, "pagepath", "", "小程序页面路径")
+	flags.StringVar(&card.ThumbMediaID, "thumb-media-id", "", "封面图片的临时素材id")
+	if err := requireArgs(args, 1, "<openid>"); err != nil {
+		return nil, err
+	}
+	flags.Parse(args[1:])
+	if err := ensureToken(app); err != nil {
+		return nil, err
+	}
+
+	resp, errs := app.API.SendApplet(args[0], card)
+	return result(nil, resp, errs)
+}
+
+func subscribeSend(app *applet.Applet, args []string) (interface{}, error) {
+	msg := &api.SubscribeMsg{}
+	data := ""
+	flags := flag.NewFlagSet("subscribe send", flag.ExitOnError)
+	flags.StringVar(&msg.Touser, "touser", "", "接收者的openid")
+	flags.StringVar(&msg.TemplateID, "template", "", "订阅消息模板id")
+	flags.StringVar(&msg.Page, "page", "", "点击消息后跳转的页面")
+	flags.StringVar(&msg.MpSate, "state", "formal", "跳转的小程序类型 developer trial formal")
+	flags.StringVar(&msg.Lang, "lang", "zh_CN", "语言")
+	flags.StringVar(&data, "data", "{}", `模板内容，例如 {"thing1":{"value":"hello"}}`)
+	flags.Parse(args)
+
+	if err := json.Unmarshal([]byte(data), &msg.Data); err != nil {
+		return nil, err
+	}
+	if err := ensureToken(app); err != nil {
+		return nil, err
+	}
+
+	resp, errs := app.API.SendSubscribeMessage(msg)
+	return result(nil, resp, errs)
+}
+
+func templateList(app *applet.Applet, args []string) (interface{}, error) {
+	flags := flag.NewFlagSet("template list", flag.ExitOnError)
+	offset := flags.Uint("offset", 0, "偏移")
+	count := flags.Uint("count", 20, "数量")
+	flags.Parse(args)
+	if err := ensureToken(app); err != nil {
+		return nil, err
+	}
+
+	return result(app.API.GetTemplateList(*offset, *count))
+}
+
+func wxacode(app *applet.Applet, args []string) (interface{}, error) {
+	code := &api.WxaCodeUnlimited{}
+	flags := flag.NewFlagSet("wxacode", flag.ExitOnError)
+	flags.StringVar(&code.Scene, "scene", "", "场景值")
+	flags.StringVar(&code.Page, "page", "", "页面路径")
+	flags.StringVar(&code.EnvVersion, "env", "", "release trial develop")
+	flags.IntVar(&code.Width, "width", 0, "二维码宽度")
+	flags.BoolVar(&code.IsHyaline, "hyaline", false, "透明底色")
+	filename := flags.String("o", "wxacode.png", "保存的文件")
+	flags.Parse(args)
+	if err := ensureToken(app); err != nil {
+		return nil, err
+	}
+
+	image, resp, errs := app.API.GetUnlimitedWxaCode(code)
+	if len(errs) != 0 || resp.ErrCode != 0 {
+		return result(nil, resp, errs)
+	}
+
+	if err := ioutil.WriteFile(*filename, image, 0644); err != nil {
+		return nil, err
+	}
+
+	return result(map[string]interface{}{"file": *filename, "size": len(image)}, resp, errs)
+}
+
+func schemeGenerate(app *applet.Applet, args []string) (interface{}, error) {
+	jump := &api.SchemeJumpWxa{}
+	scheme := &api.SchemeGenerate{JumpWxa: jump}
+	flags := flag.NewFlagSet("scheme generate", flag.ExitOnError)
+	flags.StringVar(&jump.Path, "path", "", "页面路径")
+	flags.StringVar(&jump.Query, "query", "", "进入小程序时的query")
+	flags.StringVar(&jump.EnvVersion, "env", "", "release trial develop")
+	flags.IntVar(&scheme.ExpireInterval, "expire-days", 0, "失效天数，0表示使用默认")
+	flags.Parse(args)
+	if scheme.ExpireInterval > 0 {
+		scheme.IsExpire = true
+		scheme.ExpireType = 1
+	}
+	if err := ensureToken(app); err != nil {
+		return nil, err
+	}
+
+	return result(app.API.GenerateScheme(scheme))
+}
+
+func cryptoEncrypt(app *applet.Applet, args []string) (interface{}, error) {
+	if err := requireArgs(args, 1, "<text>"); err != nil {
+		return nil, err
+	}
+
+	if err := app.Crypto.CheckKey(); err != nil {
+		return nil, err
+	}
+
+	return map[string]string{"encrypt": app.Crypto.Encrypt(args[0])}, nil
+}
+
+func cryptoDecrypt(app *applet.Applet, args []string) (interface{}, error) {
+	if err := requireArgs(args, 1, "<text>"); err != nil {
+		return nil, err
+	}
+
+	text, err := app.Crypto.Decrypt(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{"text": text}, nil
+}
+
+func cryptoSign(app *applet.Applet, args []string) (interface{}, error) {
+	if err := requireArgs(args, 2, "<timestamp> <nonce> [msg_encrypt]"); err != nil {
+		return nil, err
+	}
+
+	signs := map[string]string{"signature": app.Crypto.CalcSignature(args[0], args[1])}
+	if len(args) > 2 {
+		signs["msg_signature"] = app.Crypto.CalcMsgSignature(args[0], args[1], args[2])
+	}
+
+	return signs, nil
+}
+
+// pushVerify 使用消息信使校验并解析一次消息推送，没有消息体时按服务器地址校验处理
+func pushVerify(app *applet.Applet, args []string) (interface{}, error) {
+	flags := flag.NewFlagSet("push verify", flag.ExitOnError)
+	query := flags.String("query", "", "推送地址的query，例如 signature=xx&timestamp=xx&nonce=xx")
+	bodyFile := flags.String("body", "", "推送的消息体文件，-表示标准输入")
+	contentType := flags.String("content-type", "", "推送的Content-Type，默认自动识别")
+	flags.Parse(args)
+
+	var rawBody []byte
+	var err error
+	if *bodyFile == "-" {
+		rawBody, err = ioutil.ReadAll(os.Stdin)
+	} else if *bodyFile != "" {
+		rawBody, err = ioutil.ReadFile(*bodyFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	method := http.MethodGet
+	if len(rawBody) != 0 {
+		method = http.MethodPost
+	}
+
+	request := httptest.NewRequest(method, "/?"+*query, bytes.NewReader(rawBody))
+	request.Header.Set("Content-Type", *contentType)
+
+	var pushed *message.Message
+	messenger := message.NewWechatMessager(app.Crypto).SetLogger(discard{}).RegisterHandler(func(msg *message.Message, err error) string {
+		pushed = msg
+		return ""
+	})
+
+	recorder := httptest.NewRecorder()
+	messenger.ServeHTTP(recorder, request)
+
+	return map[string]interface{}{
+		"valid":   recorder.Code == http.StatusOK,
+		"status":  recorder.Code,
+		"message": pushed,
+	}, nil
+}
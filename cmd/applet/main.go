@@ -0,0 +1,169 @@
+// Command applet 小程序日常运维命令行工具
+//
+// 凭证从环境变量(APPLET_APP_ID、APPLET_APP_KEY等)或-config指定的配置文件读取，结果以json输出
+//
+//	applet token get|renew
+//	applet session <code>
+//	applet send text|image|link|applet <openid> ...
+//	applet subscribe send -touser <openid> -template <id> -data <json>
+//	applet template list
+//	applet wxacode -scene <scene> -o <file>
+//	applet scheme generate -path <path>
+//	applet crypto encrypt|decrypt|sign ...
+//	applet push verify -query <query> [-body <file>]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/amazing-gao/applet"
+	"github.com/amazing-gao/applet/api"
+)
+
+const usage = `usage: applet [-config file] [-app appid] <command> [args]
+
+commands:
+  token get|renew                       获取或刷新access_token
+  session <code>                        code换取openid、session_key
+  send text <openid> <content>          发送客服文本消息
+  send image <openid> <media_id>        发送客服图片消息
+  send link <openid> [flags]            发送客服图文链接
+  send applet <openid> [flags]          发送客服小程序卡片
+  subscribe send [flags]                发送订阅消息
+  template list [flags]                 获取模板列表
+  wxacode [flags]                       生成小程序码并保存到文件
+  scheme generate [flags]               生成小程序scheme码
+  crypto encrypt <text>                 加密消息
+  crypto decrypt <text>                 解密消息
+  crypto sign <timestamp> <nonce> [msg] 计算签名
+  push verify [flags]                   校验并解析消息推送
+`
+
+type (
+	// command 子命令
+	command func(app *applet.Applet, args []string) (interface{}, error)
+
+	// output 接口调用结果
+	output struct {
+		Resp   *api.WechatResp `json:"resp,omitempty"`
+		Result interface{}     `json:"result,omitempty"`
+	}
+)
+
+var commands = map[string]map[string]command{
+	"token":     {"get": tokenGet, "renew": tokenRenew},
+	"session":   {"": session},
+	"send":      {"text": sendText, "image": sendImage, "link": sendLink, "applet": sendApplet},
+	"subscribe": {"send": subscribeSend},
+	"template":  {"list": templateList},
+	"wxacode":   {"": wxacode},
+	"scheme":    {"generate": schemeGenerate},
+	"crypto":    {"encrypt": cryptoEncrypt, "decrypt": cryptoDecrypt, "sign": cryptoSign},
+	"push":      {"verify": pushVerify},
+}
+
+func main() {
+	configFile := flag.String("config", os.Getenv("APPLET_CONFIG"), "配置文件，支持json、yaml、toml")
+	appID := flag.String("app", "", "多小程序配置时使用的appid")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 || commands[args[0]] == nil {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	subCommands := commands[args[0]]
+	cmd, args := subCommands[""], args[1:]
+	if cmd == nil {
+		if len(args) == 0 || subCommands[args[0]] == nil {
+			flag.Usage()
+			os.Exit(2)
+		}
+		cmd, args = subCommands[args[0]], args[1:]
+	}
+
+	app, err := newApplet(*configFile, *appID)
+	if err != nil {
+		fail(err)
+	}
+
+	result, err := cmd(app, args)
+	if err != nil {
+		fail(err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(result)
+}
+
+// newApplet 根据配置文件或环境变量新建小程序实例，token缓存在本地文件
+func newApplet(configFile, appID string) (*applet.Applet, error) {
+	var cfg *applet.Config
+	var err error
+	if configFile != "" {
+		cfg, err = applet.LoadConfig(configFile)
+	} else {
+		cfg, err = applet.LoadConfigFromEnv()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	apps, _ := cfg.Load()
+	appCfg := apps[0]
+	if appID != "" {
+		appCfg = nil
+		for _, app := range apps {
+			if app.AppID == appID {
+				appCfg = app
+			}
+		}
+		if appCfg == nil {
+			return nil, fmt.Errorf("appID not configured: %s", appID)
+		}
+	}
+
+	tokenStore, err := newFileTokenStore(appCfg.AppID)
+	if err != nil {
+		return nil, err
+	}
+
+	return applet.NewAppletFromConfig(appCfg, applet.WithTokenStore(tokenStore), applet.WithLogger(discard{}))
+}
+
+// result 将接口调用结果转换为输出，errs不为空时返回错误
+func result(data interface{}, resp *api.WechatResp, errs []error) (interface{}, error) {
+	if len(errs) != 0 {
+		return nil, errs[0]
+	}
+
+	if resp != nil && resp.ErrCode != 0 {
+		return &output{Resp: resp}, nil
+	}
+
+	return &output{Resp: resp, Result: data}, nil
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "applet:", err)
+	os.Exit(1)
+}
+
+func requireArgs(args []string, count int, names string) error {
+	if len(args) < count {
+		return fmt.Errorf("missing arguments: %s", names)
+	}
+
+	return nil
+}
+
+// discard 丢弃接口日志，保证标准输出为json
+type discard struct{}
+
+func (discard) Printf(format string, v ...interface{}) {}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/amazing-gao/applet"
+)
+
+type (
+	// fileTokenStore 缓存在本地文件的token存储器，避免每次执行都重新生成token
+	fileTokenStore struct {
+		filename string
+	}
+
+	fileToken struct {
+		Token     string    `json:"access_token"`
+		ExpiresIn int       `json:"expires_in"`
+		ExpireAt  time.Time `json:"expire_at"`
+	}
+)
+
+func newFileTokenStore(appID string) (*fileTokenStore, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	dir = filepath.Join(dir, "applet")
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &fileTokenStore{filename: filepath.Join(dir, appID+".token.json")}, nil
+}
+
+func (store *fileTokenStore) Get() (string, int, time.Time) {
+	token := &fileToken{}
+	raw, err := ioutil.ReadFile(store.filename)
+	if err != nil || json.Unmarshal(raw, token) != nil {
+		return "", 0, time.Time{}
+	}
+
+	return token.Token, token.ExpiresIn, token.ExpireAt
+}
+
+func (store *fileTokenStore) Set(token string, expiresIn int, expireAt time.Time) {
+	raw, _ := json.Marshal(&fileToken{Token: token, ExpiresIn: expiresIn, ExpireAt: expireAt})
+	ioutil.WriteFile(store.filename, raw, 0600)
+}
+
+// ensureToken 本地缓存的token过期时重新生成
+func ensureToken(app *applet.Applet) error {
+	if _, _, expireAt := app.API.GetTokenInfo(); time.Now().Before(expireAt) {
+		return nil
+	}
+
+	resp, errs := app.API.RenewToken()
+	if len(errs) != 0 {
+		return errs[0]
+	}
+
+	if resp != nil && resp.ErrCode != 0 {
+		return fmt.Errorf("renew token failed, errcode:%d errmsg:%s", resp.ErrCode, resp.ErrMsg)
+	}
+
+	return nil
+}
+
+func tokenGet(app *applet.Applet, args []string) (interface{}, error) {
+	if err := ensureToken(app); err != nil {
+		return nil, err
+	}
+
+	token, expiresIn, expireAt := app.API.GetTokenInfo()
+	return &fileToken{Token: token, ExpiresIn: expiresIn, ExpireAt: expireAt}, nil
+}
+
+func tokenRenew(app *applet.Applet, args []string) (interface{}, error) {
+	resp, errs := app.API.RenewToken()
+	if len(errs) != 0 {
+		return nil, errs[0]
+	}
+	if resp.ErrCode != 0 {
+		return &output{Resp: resp}, nil
+	}
+
+	token, expiresIn, expireAt := app.API.GetTokenInfo()
+	return &fileToken{Token: token, ExpiresIn: expiresIn, ExpireAt: expireAt}, nil
+}
@@ -0,0 +1,93 @@
+package applet
+
+import (
+	"github.com/amazing-gao/applet/api"
+	"github.com/amazing-gao/applet/crypto"
+	"github.com/amazing-gao/applet/message"
+)
+
+const (
+	// InfoTypeComponentVerifyTicket 第三方平台验证票据推送
+	InfoTypeComponentVerifyTicket = "component_verify_ticket"
+)
+
+type (
+	// Component 第三方平台
+	Component struct {
+		appID          string                   // 第三方平台appid
+		appSecret      string                   // 第三方平台秘钥
+		appToken       string                   // 消息校验token
+		encodingAESKey string                   // 消息加解密秘钥
+		handler        message.Handler          // 授权事件处理器
+		API            *api.WechatComponent     // 第三方平台接口
+		Crypto         *crypto.WechatCrypto     // 微信加密解密工具
+		Messager       *message.WechatMessenger // 授权事件信使，自动保存component_verify_ticket
+	}
+)
+
+// NewComponent 新建一个第三方平台实例，opts中的token存储器用于保存component_access_token
+func NewComponent(appID, appSecret, appToken, encodingAESKey string, ticketStore api.ComponentTicketStore, refreshTokenStore api.AuthorizerRefreshTokenStore, opts ...Option) (*Component, error) {
+	if err := crypto.CheckEncodingAESKey(encodingAESKey); err != nil {
+		return nil, err
+	}
+
+	s := &settings{}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	apix, err := api.NewWechatComponent(appID, appSecret, ticketStore, refreshTokenStore, s.apiOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	comp := &Component{
+		appID:          appID,
+		appSecret:      appSecret,
+		appToken:       appToken,
+		encodingAESKey: encodingAESKey,
+		API:            apix,
+		Crypto:         crypto.NewWechatCrypto(appID, appToken, encodingAESKey),
+	}
+	comp.Messager = message.NewWechatMessager(comp.Crypto).RegisterHandler(comp.handle)
+	for _, configure := range s.messengerSettings {
+		configure(comp.Messager)
+	}
+
+	return comp, nil
+}
+
+// RegisterHandler 注册授权事件处理器，component_verify_ticket由第三方平台自动处理
+func (comp *Component) RegisterHandler(handler message.Handler) *Component {
+	comp.handler = handler
+
+	return comp
+}
+
+// NewAuthorizerApplet 新建一个代授权的小程序实例，可直接使用已有的API方法
+// 授权方的消息推送使用第三方平台的token和加密秘钥
+func (comp *Component) NewAuthorizerApplet(authorizerAppID string, tokenStore api.WechatTokenStore) *Applet {
+	return &Applet{
+		appID:          authorizerAppID,
+		appToken:       comp.appToken,
+		encodingAESKey: comp.encodingAESKey,
+		API:            comp.API.NewAuthorizerAPI(authorizerAppID, tokenStore),
+		Crypto:         comp.Crypto,
+		Messager:       message.NewWechatMessager(comp.Crypto),
+	}
+}
+
+func (comp *Component) handle(msg *message.Message, err error) string {
+	if msg.InfoType == InfoTypeComponentVerifyTicket {
+		comp.API.SetTicket(msg.ComponentVerifyTicket)
+		return ""
+	}
+
+	if comp.handler != nil {
+		return comp.handler(msg, err)
+	}
+
+	return ""
+}
@@ -0,0 +1,186 @@
+package applet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/amazing-gao/applet/crypto"
+	"gopkg.in/yaml.v2"
+)
+
+type (
+	// Config 小程序配置文件，顶层字段配置单个小程序，apps配置多个小程序
+	// 支持json、yaml、toml格式，环境变量会覆盖顶层的单个小程序配置
+	Config struct {
+		AppletConfig `yaml:",inline"`
+		Apps         []*AppletConfig `json:"apps" yaml:"apps" toml:"apps"`
+	}
+)
+
+// LoadConfig 按文件扩展名加载配置文件，并应用环境变量、读取秘钥文件、校验配置
+func LoadConfig(filename string) (*Config, error) {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		err = json.Unmarshal(raw, cfg)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, cfg)
+	case ".toml":
+		err = toml.Unmarshal(raw, cfg)
+	default:
+		err = fmt.Errorf("unsupport config file: %s", filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, cfg.complete()
+}
+
+// LoadConfigFromEnv 从环境变量加载单个小程序配置，例如APPLET_APP_ID
+func LoadConfigFromEnv() (*Config, error) {
+	cfg := &Config{}
+
+	return cfg, cfg.complete()
+}
+
+// NewFileSource 基于配置文件的小程序配置来源，每次Reload都会重新读取文件
+func NewFileSource(filename string) AppletSource {
+	return AppletSourceFunc(func() ([]*AppletConfig, error) {
+		cfg, err := LoadConfig(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		return cfg.Load()
+	})
+}
+
+// Load 获取配置的全部小程序，实现AppletSource
+func (cfg *Config) Load() ([]*AppletConfig, error) {
+	configs := []*AppletConfig{}
+	if cfg.AppID != "" {
+		configs = append(configs, &cfg.AppletConfig)
+	}
+
+	return append(configs, cfg.Apps...), nil
+}
+
+// Validate 校验配置
+func (cfg *Config) Validate() error {
+	configs, _ := cfg.Load()
+	if len(configs) == 0 {
+		return fmt.Errorf("no applet configured")
+	}
+
+	appIDs := map[string]bool{}
+	for _, app := range configs {
+		if err := app.Validate(); err != nil {
+			return err
+		}
+		if appIDs[app.AppID] {
+			return fmt.Errorf("duplicate appID: %s", app.AppID)
+		}
+		appIDs[app.AppID] = true
+	}
+
+	return nil
+}
+
+// Validate 校验单个小程序配置
+func (cfg *AppletConfig) Validate() error {
+	if cfg.AppID == "" {
+		return fmt.Errorf("appID is empty")
+	}
+
+	if cfg.EncodingAESKey != "" {
+		if err := crypto.CheckEncodingAESKey(cfg.EncodingAESKey); err != nil {
+			return fmt.Errorf("appID:%s %s", cfg.AppID, err)
+		}
+	}
+
+	return nil
+}
+
+// complete 应用环境变量、读取秘钥文件并校验配置
+func (cfg *Config) complete() error {
+	loadEnv(&cfg.AppletConfig)
+
+	configs, _ := cfg.Load()
+	for _, app := range configs {
+		if err := app.readSecretFiles(); err != nil {
+			return err
+		}
+	}
+
+	return cfg.Validate()
+}
+
+// readSecretFiles 读取以文件形式挂载的秘钥，例如k8s secret
+func (cfg *AppletConfig) readSecretFiles() error {
+	files := []struct {
+		filename string
+		value    *string
+	}{
+		{cfg.AppKeyFile, &cfg.AppKey},
+		{cfg.AppTokenFile, &cfg.AppToken},
+		{cfg.EncodingAESKeyFile, &cfg.EncodingAESKey},
+	}
+
+	for _, file := range files {
+		if file.filename == "" {
+			continue
+		}
+
+		raw, err := ioutil.ReadFile(file.filename)
+		if err != nil {
+			return err
+		}
+		*file.value = strings.TrimSpace(string(raw))
+	}
+
+	return nil
+}
+
+// loadEnv 按env标签从环境变量加载配置，环境变量优先于配置文件
+func loadEnv(cfg *AppletConfig) {
+	value := reflect.ValueOf(cfg).Elem()
+	for index := 0; index < value.NumField(); index++ {
+		name := value.Type().Field(index).Tag.Get("env")
+		if name == "" {
+			continue
+		}
+
+		if env, ok := os.LookupEnv(name); ok {
+			value.Field(index).SetString(env)
+		}
+	}
+}
+
+// NewAppletFromConfig 根据小程序配置新建一个小程序实例，opts可覆盖配置
+func NewAppletFromConfig(cfg *AppletConfig, opts ...Option) (*Applet, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	options := []Option{WithSecret(cfg.AppKey), WithToken(cfg.AppToken)}
+	if cfg.EncodingAESKey != "" {
+		options = append(options, WithEncodingAESKey(cfg.EncodingAESKey))
+	}
+	if cfg.BaseURL != "" {
+		options = append(options, WithBaseURL(cfg.BaseURL))
+	}
+
+	return NewApplet(cfg.AppID, append(options, opts...)...)
+}
@@ -9,9 +9,19 @@ import (
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 )
 
+var (
+	// ErrInvalidCiphertext 密文格式错误，无法解密
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+	// ErrAppIDMismatch 密文末尾的appid与当前小程序不一致
+	ErrAppIDMismatch = errors.New("ciphertext appid mismatch")
+	// ErrInvalidKey 消息加密秘钥缺失或格式错误，无法加密、解密
+	ErrInvalidKey = errors.New("invalid encodingAESKey")
+)
+
 type (
 	// WechatCrypto 微信加密
 	// examples:
@@ -43,19 +53,46 @@ type (
 )
 
 // NewWechatCrypto 新建一个微信加密、解密工具
+// 明文模式下encodingAESKey可为空，此时只能校验签名
 func NewWechatCrypto(appID, token, encodingAESKey string) *WechatCrypto {
 	r, _ := base64.StdEncoding.DecodeString(encodingAESKey + "=")
-	return &WechatCrypto{
+	wc := &WechatCrypto{
 		token:          token,
 		appID:          []byte(appID),
 		encodingAESKey: []byte(r),
-		iv:             ([]byte(r))[0:16],
 	}
+	if len(r) >= 16 {
+		wc.iv = r[0:16]
+	}
+
+	return wc
+}
+
+// CheckEncodingAESKey 校验消息加密秘钥，应为43位的base64字符串
+func CheckEncodingAESKey(encodingAESKey string) error {
+	if len(encodingAESKey) != 43 {
+		return fmt.Errorf("invalid encodingAESKey length: %d", len(encodingAESKey))
+	}
+
+	if _, err := base64.StdEncoding.DecodeString(encodingAESKey + "="); err != nil {
+		return fmt.Errorf("invalid encodingAESKey: %s", err)
+	}
+
+	return nil
+}
+
+// CheckKey 校验消息加密秘钥是否可用于加密、解密，秘钥应解码为32字节
+func (wc *WechatCrypto) CheckKey() error {
+	if len(wc.encodingAESKey) != 32 {
+		return ErrInvalidKey
+	}
+
+	return nil
 }
 
 // Encrypt 加密
 // 输入明文消息
-// 输出密文消息
+// 输出密文消息，调用前需通过CheckKey确认秘钥可用，秘钥无效时会panic
 func (wc *WechatCrypto) Encrypt(text string) string {
 	token := make([]byte, 16)
 	rand.Read(token)
@@ -73,21 +110,44 @@ func (wc *WechatCrypto) Encrypt(text string) string {
 
 // Decrypt 解密
 // 输入密文消息
-// 输出明文消息
-func (wc *WechatCrypto) Decrypt(text string) string {
-	block, error := aes.NewCipher(wc.encodingAESKey)
-	if error != nil {
-		panic(error)
+// 输出明文消息，密文格式或填充错误时返回ErrInvalidCiphertext，末尾的appid不一致时返回ErrAppIDMismatch，秘钥无效时返回ErrInvalidKey
+//
+// 注意：旧版本的Decrypt只返回明文，遇到格式错误的密文会panic，现在额外返回error，升级时调用方需处理该错误
+// 例如 plaintext, err := wc.Decrypt(encrypted)
+func (wc *WechatCrypto) Decrypt(text string) (string, error) {
+	if err := wc.CheckKey(); err != nil {
+		return "", err
+	}
+
+	block, err := aes.NewCipher(wc.encodingAESKey)
+	if err != nil {
+		return "", err
+	}
+
+	dst, err := base64.StdEncoding.DecodeString(text)
+	if err != nil || len(dst) == 0 || len(dst)%aes.BlockSize != 0 {
+		return "", ErrInvalidCiphertext
 	}
-	B := cipher.NewCBCDecrypter(block, wc.iv)
-	dst, error := base64.StdEncoding.DecodeString(text)
 
 	s := make([]byte, len(dst))
-	B.CryptBlocks(s, dst)
-	deciphered := decode(s)
+	cipher.NewCBCDecrypter(block, wc.iv).CryptBlocks(s, dst)
+	deciphered, err := decode(s)
+	if err != nil || len(deciphered) < 20 {
+		return "", ErrInvalidCiphertext
+	}
+
 	msg := deciphered[16:]
 	length := binary.BigEndian.Uint32(msg[0:4])
-	return string(msg[4 : 4+length])
+	if uint64(length) > uint64(len(msg)-4) {
+		return "", ErrInvalidCiphertext
+	}
+
+	// 明文末尾为appid，未设置appid时不校验
+	if len(wc.appID) != 0 && !bytes.Equal(msg[4+length:], wc.appID) {
+		return "", ErrAppIDMismatch
+	}
+
+	return string(msg[4 : 4+length]), nil
 }
 
 // CheckSignature 校验微信消息是否合法
@@ -111,36 +171,56 @@ func (wc *WechatCrypto) DecryptUserInfo(sessionKey, encryptedData, rawData, iv,
 		return
 	}
 
-	sessionKeyBase64, err := base64.StdEncoding.DecodeString(sessionKey)
+	dst, err := DecryptData(sessionKey, iv, encryptedData)
 	if err != nil {
 		return
 	}
 
-	encryptedDataBase64, err := base64.StdEncoding.DecodeString(encryptedData)
+	err = json.Unmarshal(dst, user)
 	if err != nil {
 		return
 	}
 
-	ivBase64, err := base64.StdEncoding.DecodeString(iv)
+	return
+}
+
+// DecryptData 使用AES-128-CBC解密开放数据，key、iv及密文均为base64编码
+// 可用于wx.getUserInfo的用户信息、手机号及getuserencryptkey下发的用户加密数据
+func DecryptData(key, iv, encryptedData string) ([]byte, error) {
+	keyBytes, err := base64.StdEncoding.DecodeString(key)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	block, err := aes.NewCipher([]byte(sessionKeyBase64))
+	ivBytes, err := base64.StdEncoding.DecodeString(iv)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	dst := make([]byte, len(encryptedDataBase64))
-	decrypter := cipher.NewCBCDecrypter(block, ivBase64)
-	decrypter.CryptBlocks(dst, encryptedDataBase64)
+	encryptedBytes, err := base64.StdEncoding.DecodeString(encryptedData)
+	if err != nil {
+		return nil, err
+	}
 
-	err = json.Unmarshal(pkcs7UnPadding(dst), user)
+	block, err := aes.NewCipher(keyBytes)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	return
+	if len(ivBytes) != block.BlockSize() || len(encryptedBytes) == 0 || len(encryptedBytes)%block.BlockSize() != 0 {
+		return nil, fmt.Errorf("%s", "invalid encrypted data")
+	}
+
+	dst := make([]byte, len(encryptedBytes))
+	decrypter := cipher.NewCBCDecrypter(block, ivBytes)
+	decrypter.CryptBlocks(dst, encryptedBytes)
+
+	return pkcs7UnPadding(dst)
+}
+
+// CalcSignature 计算明文消息及服务器校验的签名
+func (wc *WechatCrypto) CalcSignature(timestamp, nonce string) string {
+	return CalcSignature([]string{timestamp, nonce, wc.token}...)
 }
 
 // CalcMsgSignature 计算消息签名
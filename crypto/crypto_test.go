@@ -0,0 +1,118 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+)
+
+const (
+	testAppID  = "wx0000000000000000"
+	testToken  = "token"
+	testAESKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+	otherKey   = "GFEDCBA9876543210zyxwvutsrqponmlkjihgfedcba"
+)
+
+// encryptRaw 使用秘钥直接加密已填充的明文，用于构造异常的密文
+func encryptRaw(t *testing.T, wc *WechatCrypto, padded []byte) string {
+	block, err := aes.NewCipher(wc.encodingAESKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, wc.iv).CryptBlocks(encrypted, padded)
+
+	return base64.StdEncoding.EncodeToString(encrypted)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	wc := NewWechatCrypto(testAppID, testToken, testAESKey)
+
+	for _, text := range []string{"", "hello", "<xml><Content>你好</Content></xml>", string(bytes.Repeat([]byte("a"), 100))} {
+		plaintext, err := wc.Decrypt(wc.Encrypt(text))
+		if err != nil || plaintext != text {
+			t.Fatalf("round trip %q got %q %v", text, plaintext, err)
+		}
+	}
+}
+
+func TestDecryptInvalidCiphertext(t *testing.T) {
+	wc := NewWechatCrypto(testAppID, testToken, testAESKey)
+
+	for _, text := range []string{"", "AAAA", "not base64!", base64.StdEncoding.EncodeToString(make([]byte, 17))} {
+		if _, err := wc.Decrypt(text); err != ErrInvalidCiphertext {
+			t.Fatalf("ciphertext %q got %v", text, err)
+		}
+	}
+}
+
+func TestDecryptBadPadding(t *testing.T) {
+	wc := NewWechatCrypto(testAppID, testToken, testAESKey)
+
+	msg := append(make([]byte, 16), 0, 0, 0, 1, 'a')
+	msg = append(msg, testAppID...)
+	padded := encode(msg)
+	for _, pad := range []byte{0, 33, 200} {
+		broken := append([]byte{}, padded...)
+		broken[len(broken)-1] = pad
+		if _, err := wc.Decrypt(encryptRaw(t, wc, broken)); err != ErrInvalidCiphertext {
+			t.Fatalf("padding %d got %v", pad, err)
+		}
+	}
+
+	// 填充字节不一致
+	broken := append([]byte{}, padded...)
+	broken[len(broken)-2]++
+	if _, err := wc.Decrypt(encryptRaw(t, wc, broken)); err != ErrInvalidCiphertext {
+		t.Fatalf("inconsistent padding got %v", err)
+	}
+}
+
+func TestDecryptLengthOverflow(t *testing.T) {
+	wc := NewWechatCrypto(testAppID, testToken, testAESKey)
+
+	msg := make([]byte, 20)
+	binary.BigEndian.PutUint32(msg[16:], 1<<31)
+	if _, err := wc.Decrypt(encryptRaw(t, wc, encode(msg))); err != ErrInvalidCiphertext {
+		t.Fatalf("length overflow got %v", err)
+	}
+}
+
+func TestDecryptWrongKey(t *testing.T) {
+	encrypted := NewWechatCrypto(testAppID, testToken, testAESKey).Encrypt("hello")
+
+	plaintext, err := NewWechatCrypto(testAppID, testToken, otherKey).Decrypt(encrypted)
+	if err == nil {
+		t.Fatalf("wrong key decrypted %q", plaintext)
+	}
+}
+
+func TestDecryptAppIDMismatch(t *testing.T) {
+	encrypted := NewWechatCrypto("wx1111111111111111", testToken, testAESKey).Encrypt("hello")
+
+	if _, err := NewWechatCrypto(testAppID, testToken, testAESKey).Decrypt(encrypted); err != ErrAppIDMismatch {
+		t.Fatalf("appid mismatch got %v", err)
+	}
+}
+
+func TestDecryptEmptyKey(t *testing.T) {
+	if _, err := NewWechatCrypto(testAppID, testToken, "").Decrypt("AAAA"); err != ErrInvalidKey {
+		t.Fatalf("empty key got %v", err)
+	}
+}
+
+func TestCheckKey(t *testing.T) {
+	if err := NewWechatCrypto(testAppID, testToken, testAESKey).CheckKey(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"", "short", "not base64!"} {
+		if err := NewWechatCrypto(testAppID, testToken, key).CheckKey(); err != ErrInvalidKey {
+			t.Fatalf("key %q got %v", key, err)
+		}
+	}
+}
@@ -33,16 +33,31 @@ func encode(text []byte) []byte {
 	return bytes.Join([][]byte{[]byte(text), fillBytes}, []byte(""))
 }
 
-func decode(text []byte) []byte {
+// decode 去除PKCS#7填充，填充长度为1~32且每个填充字节都等于填充长度
+func decode(text []byte) ([]byte, error) {
+	if len(text) == 0 {
+		return nil, fmt.Errorf("%s", "empty text")
+	}
 	pad := int(text[len(text)-1])
-	if pad < 1 || pad > 32 {
-		pad = 0
+	if pad < 1 || pad > 32 || pad > len(text) {
+		return nil, fmt.Errorf("%s", "invalid padding")
+	}
+	for _, b := range text[len(text)-pad:] {
+		if int(b) != pad {
+			return nil, fmt.Errorf("%s", "invalid padding")
+		}
 	}
-	return text[0 : len(text)-pad]
+	return text[0 : len(text)-pad], nil
 }
 
-func pkcs7UnPadding(origData []byte) []byte {
+func pkcs7UnPadding(origData []byte) ([]byte, error) {
 	length := len(origData)
+	if length == 0 {
+		return nil, fmt.Errorf("%s", "invalid padding")
+	}
 	unpadding := int(origData[length-1])
-	return origData[:(length - unpadding)]
+	if unpadding < 1 || unpadding > length {
+		return nil, fmt.Errorf("%s", "invalid padding")
+	}
+	return origData[:(length - unpadding)], nil
 }
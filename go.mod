@@ -3,10 +3,12 @@ module github.com/amazing-gao/applet
 go 1.12
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/elazarl/goproxy v0.0.0-20210110162100-a92cc753f88e // indirect
 	github.com/parnurzeal/gorequest v0.2.16
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/smartystreets/goconvey v1.6.4 // indirect
 	golang.org/x/net v0.0.0-20210119194325-5f4716e94777 // indirect
+	gopkg.in/yaml.v2 v2.4.0
 	moul.io/http2curl v1.0.0 // indirect
 )
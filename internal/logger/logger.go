@@ -0,0 +1,7 @@
+// Package logger 各子包共用的日志接口
+package logger
+
+// Logger 日志接口，兼容标准库*log.Logger
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
@@ -0,0 +1,206 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+const (
+	// OverflowBlock 队列已满时阻塞等待，直到有空位
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDrop 队列已满时丢弃消息，仍然响应success
+	OverflowDrop
+	// OverflowReject 队列已满时拒绝消息，响应503，由微信稍后重试
+	OverflowReject
+)
+
+var (
+	// ErrQueueFull 异步队列已满
+	ErrQueueFull = errors.New("message queue is full")
+	// ErrShutdown 信使已停止接收消息
+	ErrShutdown = errors.New("messenger is shut down")
+)
+
+type (
+	// OverflowPolicy 异步队列溢出策略
+	OverflowPolicy int
+
+	// AsyncOption 异步处理配置
+	AsyncOption struct {
+		Concurrency int            // 并发处理数，默认1
+		QueueSize   int            // 队列长度，默认1024
+		Overflow    OverflowPolicy // 队列溢出策略，默认阻塞
+	}
+
+	// asyncQueue 异步消息队列
+	asyncQueue struct {
+		queue    chan *Message
+		overflow OverflowPolicy
+		logger   Logger
+		closed   bool
+		stopping chan struct{}   // 停止接收时关闭，唤醒阻塞中的入队
+		once     *sync.Once      // 保证队列只关闭一次
+		locker   *sync.RWMutex   // 保护closed及senders.Add
+		senders  *sync.WaitGroup // 正在入队的请求
+		workers  *sync.WaitGroup
+	}
+)
+
+// EnableAsync 开启异步处理模式
+// 消息解密后进入队列由工作协程处理，并立即响应success
+// 异步模式下处理器的返回值（包括被动回复）会被丢弃，如需回复请调用客服消息接口
+// 重复调用时旧的工作协程会在处理完已入队的消息后退出
+func (mgr *WechatMessenger) EnableAsync(opt AsyncOption) *WechatMessenger {
+	if opt.Concurrency <= 0 {
+		opt.Concurrency = 1
+	}
+	if opt.QueueSize <= 0 {
+		opt.QueueSize = 1024
+	}
+
+	async := &asyncQueue{
+		queue:    make(chan *Message, opt.QueueSize),
+		overflow: opt.Overflow,
+		logger:   mgr.logger,
+		stopping: make(chan struct{}),
+		once:     &sync.Once{},
+		locker:   &sync.RWMutex{},
+		senders:  &sync.WaitGroup{},
+		workers:  &sync.WaitGroup{},
+	}
+
+	for index := 0; index < opt.Concurrency; index++ {
+		async.workers.Add(1)
+		go func() {
+			defer async.workers.Done()
+			for msg := range async.queue {
+				mgr.handle(msg)
+			}
+		}()
+	}
+
+	mgr.locker.Lock()
+	old := mgr.async
+	mgr.async = async
+	mgr.locker.Unlock()
+
+	if old != nil {
+		go old.close()
+	}
+
+	return mgr
+}
+
+// asyncQueue 当前的异步队列，未开启异步模式时返回nil
+func (mgr *WechatMessenger) asyncQueue() *asyncQueue {
+	defer mgr.locker.RUnlock()
+	mgr.locker.RLock()
+
+	return mgr.async
+}
+
+// enqueue 将消息放入当前的异步队列，队列在入队时被EnableAsync替换则改为放入新队列
+// 未开启异步模式时返回false
+func (mgr *WechatMessenger) enqueue(msg *Message) (bool, error) {
+	async := mgr.asyncQueue()
+	for async != nil {
+		err := async.enqueue(msg)
+		if err != ErrShutdown {
+			return true, err
+		}
+
+		next := mgr.asyncQueue()
+		if next == async {
+			return true, err
+		}
+		async = next
+	}
+
+	return false, nil
+}
+
+// Shutdown 停止接收新消息，并等待队列中的消息处理完毕
+func (mgr *WechatMessenger) Shutdown(ctx context.Context) error {
+	async := mgr.asyncQueue()
+	if async == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		async.close()
+		async.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// handle 在工作协程中处理消息，处理器的返回值会被丢弃
+func (mgr *WechatMessenger) handle(msg *Message) {
+	defer func() {
+		if r := recover(); r != nil {
+			mgr.logger.Printf("Applet.MessageHandle.Panic %v", r)
+		}
+	}()
+
+	handler, replyHandler := mgr.handlers(msg)
+	if replyHandler != nil {
+		replyHandler(msg, nil)
+	} else if handler != nil {
+		handler(msg, nil)
+	}
+}
+
+func (async *asyncQueue) enqueue(msg *Message) error {
+	async.locker.RLock()
+	if async.closed {
+		async.locker.RUnlock()
+		return ErrShutdown
+	}
+	async.senders.Add(1)
+	async.locker.RUnlock()
+	defer async.senders.Done()
+
+	// 阻塞时不持有锁，停止接收时立即返回
+	if async.overflow == OverflowBlock {
+		select {
+		case async.queue <- msg:
+			return nil
+		case <-async.stopping:
+			return ErrShutdown
+		}
+	}
+
+	select {
+	case async.queue <- msg:
+		return nil
+	default:
+	}
+
+	if async.overflow == OverflowDrop {
+		async.logger.Printf("Applet.MessageHandle.Drop from:%s msgID:%d", msg.FromUserName, msg.MsgID)
+		return nil
+	}
+
+	return ErrQueueFull
+}
+
+// close 停止接收新消息，等待正在入队的请求返回后关闭队列，工作协程处理完剩余消息后退出
+func (async *asyncQueue) close() {
+	async.once.Do(func() {
+		async.locker.Lock()
+		async.closed = true
+		close(async.stopping)
+		async.locker.Unlock()
+
+		async.senders.Wait()
+		close(async.queue)
+	})
+}
@@ -0,0 +1,107 @@
+package message
+
+import (
+	"container/list"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type (
+	// DedupeStore 消息去重存储器，可基于redis等实现多实例共享
+	DedupeStore interface {
+		// Mark 标记key已处理，首次标记返回true，重复标记返回false
+		Mark(key string) bool
+		// Unmark 撤销标记，消息未能处理时调用，使微信的重试可以再次处理
+		Unmark(key string)
+	}
+
+	// MemoryDedupeStore 基于内存的LRU去重存储器，超过ttl的key视为未处理
+	MemoryDedupeStore struct {
+		capacity int
+		ttl      time.Duration
+		items    map[string]*list.Element
+		lru      *list.List
+		locker   *sync.Mutex
+	}
+
+	dedupeItem struct {
+		key      string
+		expireAt time.Time
+	}
+)
+
+// NewMemoryDedupeStore 新建一个内存去重存储器
+// capacity 最多保存的key数量，超出时淘汰最久未使用的key
+// ttl key的有效期，微信最多重试3次，15秒以上即可
+func NewMemoryDedupeStore(capacity int, ttl time.Duration) *MemoryDedupeStore {
+	return &MemoryDedupeStore{
+		capacity: capacity,
+		ttl:      ttl,
+		items:    map[string]*list.Element{},
+		lru:      list.New(),
+		locker:   &sync.Mutex{},
+	}
+}
+
+// Mark 标记key已处理
+func (store *MemoryDedupeStore) Mark(key string) bool {
+	defer store.locker.Unlock()
+	store.locker.Lock()
+
+	now := time.Now()
+	if elem, ok := store.items[key]; ok {
+		item := elem.Value.(*dedupeItem)
+		store.lru.MoveToFront(elem)
+		if now.Before(item.expireAt) {
+			return false
+		}
+		item.expireAt = now.Add(store.ttl)
+		return true
+	}
+
+	store.items[key] = store.lru.PushFront(&dedupeItem{key: key, expireAt: now.Add(store.ttl)})
+	for store.capacity > 0 && store.lru.Len() > store.capacity {
+		oldest := store.lru.Back()
+		store.lru.Remove(oldest)
+		delete(store.items, oldest.Value.(*dedupeItem).key)
+	}
+
+	return true
+}
+
+// Unmark 撤销标记
+func (store *MemoryDedupeStore) Unmark(key string) {
+	defer store.locker.Unlock()
+	store.locker.Lock()
+
+	if elem, ok := store.items[key]; ok {
+		store.lru.Remove(elem)
+		delete(store.items, key)
+	}
+}
+
+// SetDedupeStore 设置消息去重存储器，重复推送的消息直接响应success
+func (mgr *WechatMessenger) SetDedupeStore(store DedupeStore) *WechatMessenger {
+	mgr.dedupe = store
+
+	return mgr
+}
+
+// unmark 消息未能处理时撤销去重标记
+func (mgr *WechatMessenger) unmark(msg *Message) {
+	if mgr.dedupe != nil {
+		mgr.dedupe.Unmark(dedupeKey(msg))
+	}
+}
+
+// dedupeKey 消息去重的key，普通消息使用MsgId，事件使用FromUserName+CreateTime+Event
+// 第三方平台的推送没有FromUserName和Event，使用InfoType区分
+func dedupeKey(msg *Message) string {
+	if msg.MsgID != 0 {
+		return strconv.Itoa(msg.MsgID)
+	}
+
+	return fmt.Sprintf("%s:%d:%s%s", msg.FromUserName, msg.CreateTime, msg.Event, msg.InfoType)
+}
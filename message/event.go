@@ -0,0 +1,69 @@
+package message
+
+const (
+	// EventUserEnterTempsession 用户进入客服会话
+	EventUserEnterTempsession = "user_enter_tempsession"
+	// EventAddExpressPath 运单轨迹更新
+	EventAddExpressPath = "add_express_path"
+	// EventTradeManageRemindAccessAPI 提醒接入发货信息管理服务API
+	EventTradeManageRemindAccessAPI = "trade_manage_remind_access_api"
+	// EventTradeManageRemindShipping 提醒需要上传发货信息
+	EventTradeManageRemindShipping = "trade_manage_remind_shipping"
+	// EventTradeManageOrderSettlement 订单将要结算或已经结算
+	EventTradeManageOrderSettlement = "trade_manage_order_settlement"
+)
+
+type (
+	// eventHandler 事件处理器，handler和replyHandler只设置其一
+	eventHandler struct {
+		handler      Handler
+		replyHandler ReplyHandler
+	}
+
+	// ExpressAction 运单轨迹节点
+	ExpressAction struct {
+		ActionTime int64  `json:"ActionTime" xml:"ActionTime"` // 轨迹节点Unix时间戳
+		ActionType int    `json:"ActionType" xml:"ActionType"` // 轨迹节点类型
+		ActionMsg  string `json:"ActionMsg" xml:"ActionMsg"`   // 轨迹节点详情
+	}
+)
+
+// HandleEvent 按事件类型注册处理器，优先于RegisterHandler和RegisterReplyHandler注册的处理器
+// 事件处理器的返回值原样响应给微信服务器，为空时响应success
+// 可在服务运行时注册，会覆盖同一事件已注册的处理器
+func (mgr *WechatMessenger) HandleEvent(event string, handler Handler) *WechatMessenger {
+	return mgr.setEventHandler(event, &eventHandler{handler: handler})
+}
+
+// HandleEventReply 按事件类型注册支持被动回复的处理器，例如将user_enter_tempsession转发到客服
+// 回复按推送的格式序列化，安全模式下加密，返回nil时响应success
+func (mgr *WechatMessenger) HandleEventReply(event string, replyHandler ReplyHandler) *WechatMessenger {
+	return mgr.setEventHandler(event, &eventHandler{replyHandler: replyHandler})
+}
+
+func (mgr *WechatMessenger) setEventHandler(event string, handler *eventHandler) *WechatMessenger {
+	defer mgr.locker.Unlock()
+	mgr.locker.Lock()
+
+	if mgr.eventHandlers == nil {
+		mgr.eventHandlers = map[string]*eventHandler{}
+	}
+	mgr.eventHandlers[event] = handler
+
+	return mgr
+}
+
+// handlers 查找消息对应的处理器，已注册事件处理器时优先使用
+func (mgr *WechatMessenger) handlers(msg *Message) (Handler, ReplyHandler) {
+	if msg.MsgType == "event" {
+		mgr.locker.RLock()
+		event := mgr.eventHandlers[msg.Event]
+		mgr.locker.RUnlock()
+
+		if event != nil {
+			return event.handler, event.replyHandler
+		}
+	}
+
+	return mgr.messageHandler, mgr.replyHandler
+}
@@ -1,17 +1,88 @@
 package message
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"mime"
+	"strings"
 )
 
-func unmarshal(contentType string, rawMsg []byte, msg *Message) error {
-	if contentType == "application/json" || contentType == "text/json" {
+const (
+	// FormatAuto 根据Content-Type和消息内容自动识别数据格式
+	FormatAuto Format = iota
+	// FormatXML XML数据格式
+	FormatXML
+	// FormatJSON JSON数据格式
+	FormatJSON
+)
+
+type (
+	// Format 消息推送的数据格式，与小程序管理后台配置一致
+	Format int
+)
+
+// ContentType 数据格式对应的Content-Type
+func (format Format) ContentType() string {
+	if format == FormatJSON {
+		return "application/json; charset=utf-8"
+	}
+
+	return "text/xml; charset=utf-8"
+}
+
+// detectFormat 识别消息的数据格式
+// 优先 管理后台配置的格式
+// 再次 Content-Type，兼容charset等参数及+json、+xml后缀
+// 再次 嗅探消息内容
+func detectFormat(configured Format, contentType string, rawMsg []byte) (Format, error) {
+	if configured != FormatAuto {
+		return configured, nil
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json") {
+			return FormatJSON, nil
+		} else if mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml") {
+			return FormatXML, nil
+		}
+	}
+
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(rawMsg, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if len(trimmed) != 0 && trimmed[0] == '<' {
+		return FormatXML, nil
+	} else if len(trimmed) != 0 && trimmed[0] == '{' {
+		return FormatJSON, nil
+	}
+
+	return FormatAuto, fmt.Errorf("unsupport content type: %s", contentType)
+}
+
+func unmarshal(format Format, rawMsg []byte, msg interface{}) error {
+	if format == FormatJSON {
 		return json.Unmarshal(rawMsg, msg)
-	} else if contentType == "application/xml" || contentType == "text/xml" {
+	} else if format == FormatXML {
 		return xml.Unmarshal(rawMsg, msg)
 	}
 
-	return fmt.Errorf("unsupport content type: %s", contentType)
+	return fmt.Errorf("unsupport data format: %d", format)
+}
+
+func marshal(format Format, msg interface{}) ([]byte, error) {
+	if format == FormatJSON {
+		return json.Marshal(msg)
+	} else if format == FormatXML {
+		return xml.Marshal(msg)
+	}
+
+	return nil, fmt.Errorf("unsupport data format: %d", format)
+}
+
+func randomNonce() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
 }
@@ -1,20 +1,47 @@
 package message
 
 import (
+	"bytes"
 	"encoding/xml"
-	"fmt"
+	"errors"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
+	"sync"
+	"time"
 
 	"github.com/amazing-gao/applet/crypto"
+	"github.com/amazing-gao/applet/internal/logger"
+)
+
+const (
+	// DefaultMaxBodySize 默认推送消息体的最大长度
+	DefaultMaxBodySize = 1 << 20
+)
+
+var (
+	// ErrBodyTooLarge 推送消息体超出长度限制
+	ErrBodyTooLarge = errors.New("message body too large")
 )
 
 type (
 	// WechatMessenger 小程序消息推送信使
 	WechatMessenger struct {
 		crypto         *crypto.WechatCrypto
-		messageHandler Handler // 客服消息事件处理器
+		messageHandler Handler                  // 客服消息事件处理器
+		eventHandlers  map[string]*eventHandler // 按事件类型注册的处理器
+		locker         *sync.RWMutex            // 保护eventHandlers及async，允许服务运行时修改
+		replyHandler   ReplyHandler             // 支持被动回复的客服消息事件处理器
+		async          *asyncQueue              // 异步处理队列
+		dedupe         DedupeStore              // 消息去重存储器
+		nonces         DedupeStore              // 随机数缓存，用于重放保护
+		replayWindow   time.Duration
+		maxBodySize    int64        // 推送消息体的最大长度
+		dataFormat     Format       // 管理后台配置的数据格式
+		securityMode   SecurityMode // 管理后台配置的消息加解密方式
+		logger         Logger
 	}
 
 	// Message 小程序消息推送
@@ -38,19 +65,77 @@ type (
 		SessionFrom  string   `json:"SessionFrom" xml:"SessionFrom"`   // event: 开发者在客服会话按钮设置的session-from属性
 		Query        string   `json:"Query" xml:"Query"`               // 搜索内容
 		Scene        int      `json:"Scene" xml:"Scene"`               // 场景值
+
+		InfoType                     string `json:"InfoType" xml:"InfoType"`                                         // 第三方平台: component_verify_ticket authorized unauthorized updateauthorized
+		ComponentVerifyTicket        string `json:"ComponentVerifyTicket" xml:"ComponentVerifyTicket"`               // 第三方平台: 验证票据
+		AuthorizerAppID              string `json:"AuthorizerAppid" xml:"AuthorizerAppid"`                           // 第三方平台: 授权方appid
+		AuthorizationCode            string `json:"AuthorizationCode" xml:"AuthorizationCode"`                       // 第三方平台: 授权码
+		AuthorizationCodeExpiredTime int    `json:"AuthorizationCodeExpiredTime" xml:"AuthorizationCodeExpiredTime"` // 第三方平台: 授权码过期时间
+		PreAuthCode                  string `json:"PreAuthCode" xml:"PreAuthCode"`                                   // 第三方平台: 预授权码
+
+		DeliveryID string          `json:"DeliveryID" xml:"DeliveryID"` // add_express_path: 快递公司ID
+		WayBillID  string          `json:"WayBillId" xml:"WayBillId"`   // add_express_path: 运单ID
+		OrderID    string          `json:"OrderId" xml:"OrderId"`       // add_express_path: 订单ID
+		Version    int             `json:"Version" xml:"Version"`       // add_express_path: 轨迹版本号
+		Count      int             `json:"Count" xml:"Count"`           // add_express_path: 轨迹节点数量
+		Actions    []ExpressAction `json:"Actions" xml:"Actions"`       // add_express_path: 轨迹节点列表
+
+		Msg                     string `json:"msg" xml:"msg"`                                             // trade_manage_*: 消息文本内容
+		TransactionID           string `json:"transaction_id" xml:"transaction_id"`                       // trade_manage_*: 微信支付订单号
+		MerchantID              string `json:"merchant_id" xml:"merchant_id"`                             // trade_manage_*: 商户号
+		SubMerchantID           string `json:"sub_merchant_id" xml:"sub_merchant_id"`                     // trade_manage_*: 子商户号
+		MerchantTradeNo         string `json:"merchant_trade_no" xml:"merchant_trade_no"`                 // trade_manage_*: 商户订单号
+		PayTime                 int64  `json:"pay_time" xml:"pay_time"`                                   // trade_manage_*: 支付成功时间，秒级时间戳
+		ShippedTime             int64  `json:"shipped_time" xml:"shipped_time"`                           // trade_manage_order_settlement: 发货时间
+		EstimatedSettlementTime int64  `json:"estimated_settlement_time" xml:"estimated_settlement_time"` // trade_manage_order_settlement: 预计结算时间
+		ConfirmReceiveMethod    int    `json:"confirm_receive_method" xml:"confirm_receive_method"`       // trade_manage_order_settlement: 确认收货方式，1手动确认，2自动确认
+		ConfirmReceiveTime      int64  `json:"confirm_receive_time" xml:"confirm_receive_time"`           // trade_manage_order_settlement: 确认收货时间
+		SettlementTime          int64  `json:"settlement_time" xml:"settlement_time"`                     // trade_manage_order_settlement: 订单结算时间
 	}
 
 	// Handler 小程序消息推送处理器
 	Handler func(*Message, error) string
+
+	// Logger 日志接口，兼容标准库*log.Logger
+	Logger = logger.Logger
 )
 
 // NewWechatMessager 新建一个微信消息信使
 func NewWechatMessager(crypto *crypto.WechatCrypto) *WechatMessenger {
 	return &WechatMessenger{
-		crypto: crypto,
+		crypto:      crypto,
+		locker:      &sync.RWMutex{},
+		maxBodySize: DefaultMaxBodySize,
+		logger:      log.New(os.Stderr, "", log.LstdFlags),
 	}
 }
 
+// SetLogger 设置日志
+func (mgr *WechatMessenger) SetLogger(logger Logger) *WechatMessenger {
+	mgr.logger = logger
+
+	return mgr
+}
+
+// SetMaxBodySize 设置推送消息体的最大长度，超出时响应413
+func (mgr *WechatMessenger) SetMaxBodySize(size int64) *WechatMessenger {
+	mgr.maxBodySize = size
+
+	return mgr
+}
+
+// MaxBodySize 推送消息体的最大长度，不大于0时不限制
+func (mgr *WechatMessenger) MaxBodySize() int64 {
+	return mgr.maxBodySize
+}
+
+// SetDataFormat 设置管理后台配置的数据格式，默认自动识别
+func (mgr *WechatMessenger) SetDataFormat(format Format) *WechatMessenger {
+	mgr.dataFormat = format
+
+	return mgr
+}
+
 // RegisterHandler 注册小程序消息推送处理器
 func (mgr *WechatMessenger) RegisterHandler(messageHandler Handler) *WechatMessenger {
 	mgr.messageHandler = messageHandler
@@ -58,19 +143,33 @@ func (mgr *WechatMessenger) RegisterHandler(messageHandler Handler) *WechatMesse
 	return mgr
 }
 
-// MessageHandleMiddleware 小程序消息处理中间件
+// ServeHTTP 实现http.Handler，可直接挂载到http.ServeMux、chi等路由
+// gin: router.Any(path, gin.WrapH(mgr))
+// echo: e.Any(path, echo.WrapHandler(mgr))
 // GET 验证消息的确来自微信服务器
 // POST 处理客服消息
-func (mgr *WechatMessenger) MessageHandleMiddleware(request *http.Request, writer http.ResponseWriter) {
-	if request.Method == "GET" {
+func (mgr *WechatMessenger) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method == http.MethodGet {
 		mgr.MessageHandleValid(request, writer)
-	} else if request.Method == "POST" {
+	} else if request.Method == http.MethodPost {
 		mgr.MessageHandle(request, writer)
 	} else {
 		mgr.MessageHandleNotSupport(request, writer)
 	}
 }
 
+// HandlerFunc 转换为http.HandlerFunc，便于挂载到只接受函数的路由
+func (mgr *WechatMessenger) HandlerFunc() http.HandlerFunc {
+	return mgr.ServeHTTP
+}
+
+// MessageHandleMiddleware 小程序消息处理中间件
+//
+// Deprecated: 参数顺序与http.HandlerFunc相反，请直接使用ServeHTTP
+func (mgr *WechatMessenger) MessageHandleMiddleware(request *http.Request, writer http.ResponseWriter) {
+	mgr.ServeHTTP(writer, request)
+}
+
 // MessageHandleValid 消息校验
 func (mgr *WechatMessenger) MessageHandleValid(request *http.Request, writer http.ResponseWriter) {
 	querys := request.URL.Query()
@@ -79,67 +178,121 @@ func (mgr *WechatMessenger) MessageHandleValid(request *http.Request, writer htt
 	signature := querys.Get("signature")
 	timestamp := querys.Get("timestamp")
 
-	if mgr.crypto.CheckSignature(timestamp, nonce, signature) {
-		writer.Write([]byte(echostr))
-	} else {
-		writer.Write([]byte("invalid"))
+	if err := mgr.verify(mgr.crypto.CheckSignature(timestamp, nonce, signature), timestamp, nonce); err != nil {
+		mgr.fail(writer, err)
+		return
 	}
+
+	writer.Write([]byte(echostr))
 }
 
 // MessageHandle 处理小程序消息推送
-//                    -> 校验密文 -> 解析密文 \
-//                  /                       \
-// 解析明文 -----> 有密文 -----------------> 处理消息 ----> 响应腾讯服务器
 //
+//	                  -> 校验msg_signature -> 解析密文 \
+//	                /                                 \
+//	解析明文 -----> 有密文 -----> 校验signature ------> 处理消息 ----> 响应腾讯服务器
 func (mgr *WechatMessenger) MessageHandle(request *http.Request, writer http.ResponseWriter) {
-	contentType := request.Header.Get("Content-Type")
 	querys := request.URL.Query()
 	nonce := querys.Get("nonce")
 	timestamp := querys.Get("timestamp")
+	signature := querys.Get("signature")
 	encryptType := querys.Get("encrypt_type")
 	msgSignature := querys.Get("msg_signature")
 
-	var err error
-	var ret string
-	for index := 0; index < 1; index++ {
-		msg := &Message{}
+	// 密文消息只在兼容模式和安全模式下接受，明文消息只在兼容模式和明文模式下接受
+	encrypted := encryptType != "" && msgSignature != ""
+	if (encrypted && mgr.securityMode == ModePlaintext) || (!encrypted && mgr.securityMode == ModeSecure) {
+		mgr.fail(writer, &RejectError{Reason: RejectModeNotAllowed})
+		return
+	}
+
+	rawMsg, err := mgr.readBody(request)
+	if err != nil {
+		mgr.fail(writer, err)
+		return
+	}
+
+	// 识别数据格式，被动回复使用相同的格式
+	format, err := detectFormat(mgr.dataFormat, request.Header.Get("Content-Type"), rawMsg)
+	if err != nil {
+		mgr.fail(writer, err)
+		return
+	}
+
+	// 解析明文消息
+	msg := &Message{}
+	if err = unmarshal(format, rawMsg, msg); err != nil {
+		mgr.fail(writer, err)
+		return
+	}
 
-		rawMsg := []byte{}
-		rawMsg, err = ioutil.ReadAll(request.Body)
+	if !encrypted {
+		// 明文消息也需校验signature，避免伪造的推送
+		if err = mgr.verify(mgr.crypto.CheckSignature(timestamp, nonce, signature), timestamp, nonce); err != nil {
+			mgr.fail(writer, err)
+			return
+		}
+	} else {
+		// 如果消息已加密，先校验消息是否合法。如果合法，再解密
+		// 如果校验失败，那么是非法消息
+		err = mgr.verify(mgr.crypto.CheckMsgSignature(timestamp, nonce, msg.EncryptMsg, msgSignature), timestamp, nonce)
 		if err != nil {
-			break
+			mgr.fail(writer, err)
+			return
 		}
 
-		// 解析明文消息
-		err = unmarshal(contentType, rawMsg, msg)
-		if err != nil {
-			break
+		// 解密密文消息
+		var encryptMsg string
+		if encryptMsg, err = mgr.crypto.Decrypt(msg.EncryptMsg); err != nil {
+			mgr.unmarkNonce(timestamp, nonce)
+			mgr.fail(writer, err)
+			return
 		}
+		if err = unmarshal(format, []byte(encryptMsg), msg); err != nil {
+			mgr.unmarkNonce(timestamp, nonce)
+			mgr.fail(writer, err)
+			return
+		}
+	}
 
-		// 如果消息已加密，先校验消息是否合法。如果合法，再解密
-		if encryptType != "" && msgSignature != "" {
-			// 如果校验失败，那么是非法消息
-			if !mgr.crypto.CheckMsgSignature(timestamp, nonce, msg.EncryptMsg, msgSignature) {
-				err = fmt.Errorf("invalid message")
-				break
-			}
+	// 重复推送的消息不再处理，未能处理时撤销去重标记和随机数，以免微信的重试被当作重复消息或重放
+	if mgr.dedupe != nil && !mgr.dedupe.Mark(dedupeKey(msg)) {
+		writer.Write([]byte("success"))
+		return
+	}
 
-			// 解密密文消息
-			encryptMsg := mgr.crypto.Decrypt(msg.EncryptMsg)
-			err = unmarshal(contentType, []byte(encryptMsg), msg)
-			if err != nil {
-				break
-			}
+	// 异步模式下消息入队后立即响应
+	if queued, err := mgr.enqueue(msg); queued {
+		if err != nil {
+			mgr.unmark(msg)
+			mgr.unmarkNonce(timestamp, nonce)
+			mgr.fail(writer, err)
+			return
 		}
+		writer.Write([]byte("success"))
+		return
+	}
 
-		// 处理消息
-		ret = mgr.messageHandler(msg, err)
+	// 处理消息，被动回复需按推送的格式回复，安全模式下需加密
+	ret := ""
+	handler, replyHandler := mgr.handlers(msg)
+	if replyHandler != nil {
+		if reply := replyHandler(msg, nil); reply != nil {
+			if ret, err = mgr.encodeReply(format, encrypted, reply); err != nil {
+				mgr.unmark(msg)
+				mgr.unmarkNonce(timestamp, nonce)
+				mgr.logger.Printf("Applet.MessageHandle.Error %v", err)
+				writer.WriteHeader(http.StatusInternalServerError)
+				writer.Write([]byte(http.StatusText(http.StatusInternalServerError)))
+				return
+			}
+			writer.Header().Set("Content-Type", format.ContentType())
+		}
+	} else if handler != nil {
+		ret = handler(msg, nil)
 	}
 
-	if err != nil {
-		log.Println("Applet.MessageHandle.Error", err)
-		ret = err.Error()
-	} else if len(ret) == 0 {
+	if len(ret) == 0 {
 		ret = "success"
 	}
 
@@ -151,3 +304,70 @@ func (mgr *WechatMessenger) MessageHandleNotSupport(request *http.Request, write
 	writer.WriteHeader(http.StatusMethodNotAllowed)
 	writer.Write([]byte("Method Not Allowed"))
 }
+
+// PeekToUserName 读取推送消息的ToUserName，并还原请求体供后续处理
+// 明文和密文消息的外层均包含ToUserName，可用于多小程序的消息路由
+// maxBodySize不大于0时不限制长度，超出时返回ErrBodyTooLarge
+func PeekToUserName(request *http.Request, maxBodySize int64) (string, error) {
+	var reader io.Reader = request.Body
+	if maxBodySize > 0 {
+		reader = io.LimitReader(request.Body, maxBodySize+1)
+	}
+
+	rawMsg, err := ioutil.ReadAll(reader)
+	request.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(rawMsg), request.Body))
+	if err == nil && maxBodySize > 0 && int64(len(rawMsg)) > maxBodySize {
+		err = ErrBodyTooLarge
+	}
+	if err != nil {
+		return "", err
+	}
+
+	format, err := detectFormat(FormatAuto, request.Header.Get("Content-Type"), rawMsg)
+	if err != nil {
+		return "", err
+	}
+
+	msg := &Message{}
+	if err = unmarshal(format, rawMsg, msg); err != nil {
+		return "", err
+	}
+
+	return msg.ToUserName, nil
+}
+
+// readBody 读取推送消息体，超出长度限制时返回ErrBodyTooLarge
+func (mgr *WechatMessenger) readBody(request *http.Request) ([]byte, error) {
+	if mgr.maxBodySize <= 0 {
+		return ioutil.ReadAll(request.Body)
+	}
+
+	rawMsg, err := ioutil.ReadAll(io.LimitReader(request.Body, mgr.maxBodySize+1))
+	if err == nil && int64(len(rawMsg)) > mgr.maxBodySize {
+		err = ErrBodyTooLarge
+	}
+
+	return rawMsg, err
+}
+
+// fail 记录错误并响应对应的状态码，不向调用方暴露内部错误
+// 签名、时间戳校验失败 403
+// 消息体过大 413
+// 异步队列已满或已停止 503
+// 消息格式错误 400
+func (mgr *WechatMessenger) fail(writer http.ResponseWriter, err error) {
+	mgr.logger.Printf("Applet.MessageHandle.Error %v", err)
+
+	// 消息体、签名正确但密文格式错误等均视为请求错误
+	status := http.StatusBadRequest
+	if _, ok := err.(*RejectError); ok {
+		status = http.StatusForbidden
+	} else if err == ErrBodyTooLarge {
+		status = http.StatusRequestEntityTooLarge
+	} else if err == ErrQueueFull || err == ErrShutdown {
+		status = http.StatusServiceUnavailable
+	}
+
+	writer.WriteHeader(status)
+	writer.Write([]byte(http.StatusText(status)))
+}
@@ -0,0 +1,297 @@
+package message_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amazing-gao/applet/applettest"
+	"github.com/amazing-gao/applet/crypto"
+	"github.com/amazing-gao/applet/message"
+)
+
+const (
+	testAppID  = "wx0000000000000000"
+	testToken  = "token"
+	testAESKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+)
+
+func newMessenger() *message.WechatMessenger {
+	return message.NewWechatMessager(crypto.NewWechatCrypto(testAppID, testToken, testAESKey)).
+		SetLogger(log.New(ioutil.Discard, "", 0))
+}
+
+func newText(msgID int) *message.Message {
+	msg := applettest.NewTextMessage("gh_test", "openid", "hello")
+	msg.MsgID = msgID
+	return msg
+}
+
+func serve(handler http.Handler, request *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestServeHTTPVerifyEchostr(t *testing.T) {
+	pusher := applettest.NewPusher(testAppID, testToken, testAESKey)
+
+	rec := serve(newMessenger(), pusher.NewVerifyRequest("echo123"))
+	if rec.Code != http.StatusOK || rec.Body.String() != "echo123" {
+		t.Fatalf("verify got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestServeHTTPBadSignature(t *testing.T) {
+	pusher := applettest.NewPusher(testAppID, testToken, testAESKey)
+
+	request := pusher.NewVerifyRequest("echo123")
+	querys := request.URL.Query()
+	querys.Set("signature", "bad")
+	request.URL.RawQuery = querys.Encode()
+	if rec := serve(newMessenger(), request); rec.Code != http.StatusForbidden {
+		t.Fatalf("verify with bad signature got %d", rec.Code)
+	}
+
+	request, _ = pusher.NewRequest(newText(1))
+	querys = request.URL.Query()
+	querys.Set("signature", "bad")
+	request.URL.RawQuery = querys.Encode()
+	if rec := serve(newMessenger(), request); rec.Code != http.StatusForbidden {
+		t.Fatalf("push with bad signature got %d", rec.Code)
+	}
+}
+
+func TestServeHTTPMalformedBody(t *testing.T) {
+	pusher := applettest.NewPusher(testAppID, testToken, testAESKey)
+
+	request, _ := pusher.NewRequest(newText(1))
+	request.Body = ioutil.NopCloser(strings.NewReader("<xml><MsgType>text"))
+	if rec := serve(newMessenger(), request); rec.Code != http.StatusBadRequest {
+		t.Fatalf("malformed body got %d", rec.Code)
+	}
+}
+
+func TestServeHTTPMalformedCiphertext(t *testing.T) {
+	wc := crypto.NewWechatCrypto(testAppID, testToken, testAESKey)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := "nonce"
+
+	for _, encrypt := range []string{"", "AAAA", "not base64!", "AAAAAAAAAAAAAAAAAAAAAA=="} {
+		querys := url.Values{}
+		querys.Set("timestamp", timestamp)
+		querys.Set("nonce", nonce)
+		querys.Set("signature", wc.CalcSignature(timestamp, nonce))
+		querys.Set("encrypt_type", "aes")
+		querys.Set("msg_signature", wc.CalcMsgSignature(timestamp, nonce, encrypt))
+
+		body := "<xml><ToUserName>gh_test</ToUserName><Encrypt>" + encrypt + "</Encrypt></xml>"
+		request := httptest.NewRequest(http.MethodPost, "/?"+querys.Encode(), strings.NewReader(body))
+		if rec := serve(newMessenger(), request); rec.Code != http.StatusBadRequest {
+			t.Fatalf("ciphertext %q got %d", encrypt, rec.Code)
+		}
+	}
+}
+
+func TestServeHTTPBodyTooLarge(t *testing.T) {
+	pusher := applettest.NewPusher(testAppID, testToken, testAESKey)
+
+	request, _ := pusher.NewRequest(newText(1))
+	request.Body = ioutil.NopCloser(bytes.NewReader(make([]byte, 64)))
+	if rec := serve(newMessenger().SetMaxBodySize(16), request); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("large body got %d", rec.Code)
+	}
+}
+
+func TestServeHTTPSecureReply(t *testing.T) {
+	pusher := applettest.NewPusher(testAppID, testToken, testAESKey).SetSecure(true)
+	mgr := newMessenger().RegisterReplyHandler(func(msg *message.Message, err error) *message.Reply {
+		return message.NewTransferCustomerServiceReply(msg)
+	})
+
+	rec, _ := pusher.Push(mgr, newText(1))
+	reply, err := pusher.DecodeReply(rec)
+	if rec.Code != http.StatusOK || err != nil || reply.MsgType != message.ReplyTransferCustomerService {
+		t.Fatalf("secure reply got %d %v %+v", rec.Code, err, reply)
+	}
+}
+
+func TestServeHTTPQueueFull(t *testing.T) {
+	pusher := applettest.NewPusher(testAppID, testToken, testAESKey)
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	mgr := newMessenger().RegisterHandler(func(*message.Message, error) string {
+		started <- struct{}{}
+		<-release
+		return ""
+	}).SetDedupeStore(message.NewMemoryDedupeStore(16, time.Minute))
+	mgr.EnableAsync(message.AsyncOption{Concurrency: 1, QueueSize: 1, Overflow: message.OverflowReject})
+	defer mgr.Shutdown(context.Background())
+	defer close(release)
+
+	if rec, _ := pusher.Push(mgr, newText(1)); rec.Code != http.StatusOK {
+		t.Fatalf("first push got %d", rec.Code)
+	}
+	<-started
+	if rec, _ := pusher.Push(mgr, newText(2)); rec.Code != http.StatusOK {
+		t.Fatalf("queued push got %d", rec.Code)
+	}
+	if rec, _ := pusher.Push(mgr, newText(3)); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("overflow push got %d", rec.Code)
+	}
+
+	// 被拒绝的消息不应被标记为已处理，微信重试时仍然返回503而不是success
+	if rec, _ := pusher.Push(mgr, newText(3)); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("retried overflow push got %d", rec.Code)
+	}
+}
+
+func TestServeHTTPShutdown(t *testing.T) {
+	pusher := applettest.NewPusher(testAppID, testToken, testAESKey)
+	mgr := newMessenger()
+	mgr.EnableAsync(message.AsyncOption{})
+	if err := mgr.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec, _ := pusher.Push(mgr, newText(1)); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("push after shutdown got %d", rec.Code)
+	}
+}
+
+func TestShutdownDeadlineWhileEnqueueBlocks(t *testing.T) {
+	pusher := applettest.NewPusher(testAppID, testToken, testAESKey)
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	defer close(release)
+	mgr := newMessenger().RegisterHandler(func(*message.Message, error) string {
+		started <- struct{}{}
+		<-release
+		return ""
+	})
+	mgr.EnableAsync(message.AsyncOption{Concurrency: 1, QueueSize: 1, Overflow: message.OverflowBlock})
+
+	pusher.Push(mgr, newText(1))
+	<-started
+	pusher.Push(mgr, newText(2))
+
+	blocked := make(chan int)
+	go func() {
+		rec, _ := pusher.Push(mgr, newText(3))
+		blocked <- rec.Code
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := mgr.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("shutdown got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("shutdown ignored the deadline, took %v", elapsed)
+	}
+	if code := <-blocked; code != http.StatusServiceUnavailable {
+		t.Fatalf("blocked push got %d", code)
+	}
+}
+
+func TestMessageHandleMiddleware(t *testing.T) {
+	pusher := applettest.NewPusher(testAppID, testToken, testAESKey)
+	received := ""
+	mgr := newMessenger().RegisterHandler(func(msg *message.Message, err error) string {
+		received = msg.Content
+		return ""
+	})
+
+	rec := httptest.NewRecorder()
+	mgr.MessageHandleMiddleware(pusher.NewVerifyRequest("echo123"), rec)
+	if rec.Code != http.StatusOK || rec.Body.String() != "echo123" {
+		t.Fatalf("middleware verify got %d %q", rec.Code, rec.Body.String())
+	}
+
+	request, _ := pusher.NewRequest(newText(1))
+	rec = httptest.NewRecorder()
+	mgr.MessageHandleMiddleware(request, rec)
+	if rec.Code != http.StatusOK || rec.Body.String() != "success" || received != "hello" {
+		t.Fatalf("middleware push got %d %q %q", rec.Code, rec.Body.String(), received)
+	}
+}
+
+func TestServeHTTPEventReply(t *testing.T) {
+	pusher := applettest.NewPusher(testAppID, testToken, testAESKey).SetSecure(true)
+	mgr := newMessenger().HandleEventReply(message.EventUserEnterTempsession, func(msg *message.Message, err error) *message.Reply {
+		return message.NewTransferCustomerServiceReply(msg)
+	})
+
+	rec, _ := pusher.Push(mgr, applettest.NewEventMessage("gh_test", "openid", message.EventUserEnterTempsession))
+	reply, err := pusher.DecodeReply(rec)
+	if rec.Code != http.StatusOK || err != nil || reply.MsgType != message.ReplyTransferCustomerService {
+		t.Fatalf("event reply got %d %v %+v", rec.Code, err, reply)
+	}
+}
+
+func TestEnableAsyncWhileServing(t *testing.T) {
+	pusher := applettest.NewPusher(testAppID, testToken, testAESKey)
+	mgr := newMessenger().RegisterHandler(func(*message.Message, error) string { return "" })
+	mgr.EnableAsync(message.AsyncOption{})
+	defer mgr.Shutdown(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for index := 0; index < 20; index++ {
+			mgr.EnableAsync(message.AsyncOption{})
+		}
+	}()
+
+	for index := 0; index < 20; index++ {
+		if rec, _ := pusher.Push(mgr, newText(index+1)); rec.Code != http.StatusOK {
+			t.Fatalf("push while re-enabling got %d", rec.Code)
+		}
+	}
+	<-done
+}
+
+func TestServeHTTPRetryAfterFailureIsNotReplay(t *testing.T) {
+	pusher := applettest.NewPusher(testAppID, testToken, testAESKey)
+	mgr := newMessenger().SetReplayProtection(time.Minute, message.NewMemoryDedupeStore(16, 2*time.Minute))
+	mgr.EnableAsync(message.AsyncOption{})
+	mgr.Shutdown(context.Background())
+
+	request, _ := pusher.NewRequest(newText(1))
+	body, _ := ioutil.ReadAll(request.Body)
+	for attempt := 0; attempt < 2; attempt++ {
+		retry := httptest.NewRequest(http.MethodPost, request.URL.String(), bytes.NewReader(body))
+		retry.Header = request.Header
+		if rec := serve(mgr, retry); rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("attempt %d got %d", attempt, rec.Code)
+		}
+	}
+
+	// 成功处理后相同的随机数视为重放
+	mgr = newMessenger().SetReplayProtection(time.Minute, message.NewMemoryDedupeStore(16, 2*time.Minute))
+	for attempt, code := range []int{http.StatusOK, http.StatusForbidden} {
+		retry := httptest.NewRequest(http.MethodPost, request.URL.String(), bytes.NewReader(body))
+		retry.Header = request.Header
+		if rec := serve(mgr, retry); rec.Code != code {
+			t.Fatalf("replay attempt %d got %d", attempt, rec.Code)
+		}
+	}
+}
+
+func TestSecurePushWithoutKey(t *testing.T) {
+	pusher := applettest.NewPusher(testAppID, testToken, "").SetSecure(true)
+
+	if _, err := pusher.NewRequest(newText(1)); err != crypto.ErrInvalidKey {
+		t.Fatalf("secure push without key got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package message
+
+import (
+	"encoding/xml"
+	"strconv"
+	"time"
+)
+
+const (
+	// ReplyTransferCustomerService 将消息转发到客服
+	ReplyTransferCustomerService = "transfer_customer_service"
+)
+
+type (
+	// Reply 被动回复消息
+	Reply struct {
+		XMLName      xml.Name `json:"-" xml:"xml"`
+		ToUserName   string   `json:"ToUserName" xml:"ToUserName"`     // 接收方帐号（收到的OpenID）
+		FromUserName string   `json:"FromUserName" xml:"FromUserName"` // 小程序的原始ID
+		CreateTime   int64    `json:"CreateTime" xml:"CreateTime"`     // 消息创建时间(整型）
+		MsgType      string   `json:"MsgType" xml:"MsgType"`           // transfer_customer_service
+	}
+
+	// EncryptReply 安全模式下加密后的被动回复消息
+	EncryptReply struct {
+		XMLName      xml.Name `json:"-" xml:"xml"`
+		Encrypt      string   `json:"Encrypt" xml:"Encrypt"`           // 加密后的回复消息
+		MsgSignature string   `json:"MsgSignature" xml:"MsgSignature"` // 消息签名
+		TimeStamp    int64    `json:"TimeStamp" xml:"TimeStamp"`       // 时间戳
+		Nonce        string   `json:"Nonce" xml:"Nonce"`               // 随机数
+	}
+
+	// ReplyHandler 小程序消息推送处理器，返回nil时响应success
+	ReplyHandler func(*Message, error) *Reply
+)
+
+// NewReply 根据推送消息生成一个被动回复消息
+func NewReply(msg *Message, msgType string) *Reply {
+	return &Reply{
+		ToUserName:   msg.FromUserName,
+		FromUserName: msg.ToUserName,
+		CreateTime:   time.Now().Unix(),
+		MsgType:      msgType,
+	}
+}
+
+// NewTransferCustomerServiceReply 生成将消息转发到网页版客服工具的被动回复消息
+func NewTransferCustomerServiceReply(msg *Message) *Reply {
+	return NewReply(msg, ReplyTransferCustomerService)
+}
+
+// RegisterReplyHandler 注册支持被动回复的小程序消息推送处理器，优先于Handler
+func (mgr *WechatMessenger) RegisterReplyHandler(replyHandler ReplyHandler) *WechatMessenger {
+	mgr.replyHandler = replyHandler
+
+	return mgr
+}
+
+// encodeReply 按推送消息的格式序列化被动回复消息，安全模式下加密并签名
+func (mgr *WechatMessenger) encodeReply(format Format, encrypted bool, reply *Reply) (string, error) {
+	rawReply, err := marshal(format, reply)
+	if err != nil || !encrypted {
+		return string(rawReply), err
+	}
+
+	if err := mgr.crypto.CheckKey(); err != nil {
+		return "", err
+	}
+
+	nonce := randomNonce()
+	timestamp := time.Now().Unix()
+	encryptMsg := mgr.crypto.Encrypt(string(rawReply))
+
+	rawReply, err = marshal(format, &EncryptReply{
+		Encrypt:      encryptMsg,
+		MsgSignature: mgr.crypto.CalcMsgSignature(strconv.FormatInt(timestamp, 10), nonce, encryptMsg),
+		TimeStamp:    timestamp,
+		Nonce:        nonce,
+	})
+
+	return string(rawReply), err
+}
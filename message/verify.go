@@ -0,0 +1,125 @@
+package message
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	// RejectInvalidSignature 签名错误
+	RejectInvalidSignature RejectReason = iota + 1
+	// RejectInvalidTimestamp 时间戳格式错误
+	RejectInvalidTimestamp
+	// RejectExpiredTimestamp 时间戳超出允许的时钟偏差
+	RejectExpiredTimestamp
+	// RejectReusedNonce 随机数已被使用，疑似重放请求
+	RejectReusedNonce
+	// RejectModeNotAllowed 消息加解密方式与配置的安全模式不符
+	RejectModeNotAllowed
+)
+
+const (
+	// ModeCompatible 兼容模式，明文和密文消息均接受，明文消息校验signature，密文消息校验msg_signature
+	ModeCompatible SecurityMode = iota
+	// ModePlaintext 明文模式，只接受明文消息，并校验signature
+	ModePlaintext
+	// ModeSecure 安全模式，只接受密文消息，并校验msg_signature
+	ModeSecure
+)
+
+type (
+	// RejectReason 推送请求被拒绝的原因
+	RejectReason int
+
+	// SecurityMode 消息加解密方式，与小程序管理后台配置一致
+	SecurityMode int
+
+	// RejectError 推送请求校验失败
+	RejectError struct {
+		Reason RejectReason
+	}
+)
+
+// String 拒绝原因描述
+func (reason RejectReason) String() string {
+	switch reason {
+	case RejectInvalidSignature:
+		return "invalid signature"
+	case RejectInvalidTimestamp:
+		return "invalid timestamp"
+	case RejectExpiredTimestamp:
+		return "expired timestamp"
+	case RejectReusedNonce:
+		return "reused nonce"
+	case RejectModeNotAllowed:
+		return "mode not allowed"
+	}
+
+	return "unknown reason"
+}
+
+func (err *RejectError) Error() string {
+	return "invalid message: " + err.Reason.String()
+}
+
+// SetSecurityMode 设置接受的消息加解密方式，默认兼容模式
+func (mgr *WechatMessenger) SetSecurityMode(mode SecurityMode) *WechatMessenger {
+	mgr.securityMode = mode
+
+	return mgr
+}
+
+// SecurityMode 接受的消息加解密方式
+func (mgr *WechatMessenger) SecurityMode() SecurityMode {
+	return mgr.securityMode
+}
+
+// SetReplayProtection 开启重放保护
+// window 允许的时钟偏差，超出则拒绝，0表示不校验时间戳
+// nonces 随机数缓存，有效期应不小于2倍的window，nil表示不校验随机数
+func (mgr *WechatMessenger) SetReplayProtection(window time.Duration, nonces DedupeStore) *WechatMessenger {
+	mgr.replayWindow = window
+	mgr.nonces = nonces
+
+	return mgr
+}
+
+// verify 校验签名结果、时间戳和随机数
+// 先校验签名，避免未签名的请求污染随机数缓存
+func (mgr *WechatMessenger) verify(signed bool, timestamp, nonce string) error {
+	if !signed {
+		return &RejectError{Reason: RejectInvalidSignature}
+	}
+
+	if mgr.replayWindow > 0 {
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return &RejectError{Reason: RejectInvalidTimestamp}
+		}
+
+		skew := time.Since(time.Unix(unix, 0))
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > mgr.replayWindow {
+			return &RejectError{Reason: RejectExpiredTimestamp}
+		}
+	}
+
+	if mgr.nonces != nil && !mgr.nonces.Mark(nonceKey(timestamp, nonce)) {
+		return &RejectError{Reason: RejectReusedNonce}
+	}
+
+	return nil
+}
+
+// unmarkNonce 推送未能处理时撤销随机数标记，微信使用相同的随机数重试时不视为重放
+func (mgr *WechatMessenger) unmarkNonce(timestamp, nonce string) {
+	if mgr.nonces != nil {
+		mgr.nonces.Unmark(nonceKey(timestamp, nonce))
+	}
+}
+
+func nonceKey(timestamp, nonce string) string {
+	return timestamp + ":" + nonce
+}
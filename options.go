@@ -0,0 +1,129 @@
+package applet
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/amazing-gao/applet/api"
+	"github.com/amazing-gao/applet/crypto"
+	"github.com/amazing-gao/applet/internal/logger"
+	"github.com/amazing-gao/applet/message"
+	"github.com/amazing-gao/applet/pay"
+)
+
+type (
+	// Option 小程序配置项
+	Option func(*settings) error
+
+	// Logger 日志接口，兼容标准库*log.Logger
+	Logger = logger.Logger
+
+	settings struct {
+		appKey            string
+		appToken          string
+		encodingAESKey    string
+		apiOptions        []api.Option
+		messengerSettings []func(*message.WechatMessenger)
+		mchID             string
+		payOptions        []pay.Option
+	}
+)
+
+// WithSecret 设置小程序秘钥
+func WithSecret(appKey string) Option {
+	return func(s *settings) error {
+		s.appKey = appKey
+		s.apiOptions = append(s.apiOptions, api.WithAppKey(appKey))
+		return nil
+	}
+}
+
+// WithToken 设置消息推送token
+func WithToken(appToken string) Option {
+	return func(s *settings) error {
+		s.appToken = appToken
+		return nil
+	}
+}
+
+// WithEncodingAESKey 设置消息加密秘钥，应为43位的base64字符串
+// 未设置时消息信使只接受明文消息，兼容模式和安全模式必须设置
+func WithEncodingAESKey(encodingAESKey string) Option {
+	return func(s *settings) error {
+		if err := crypto.CheckEncodingAESKey(encodingAESKey); err != nil {
+			return err
+		}
+		s.encodingAESKey = encodingAESKey
+		return nil
+	}
+}
+
+// WithTokenStore 设置token存储器，默认使用内存存储器
+func WithTokenStore(tokenStore api.WechatTokenStore) Option {
+	return WithAPIOptions(api.WithTokenStore(tokenStore))
+}
+
+// WithHTTPClient 设置调用接口的http客户端
+func WithHTTPClient(client *http.Client) Option {
+	return WithAPIOptions(api.WithHTTPClient(client))
+}
+
+// WithLogger 设置接口及消息信使的日志
+func WithLogger(logger Logger) Option {
+	return func(s *settings) error {
+		s.apiOptions = append(s.apiOptions, api.WithLogger(logger))
+		s.messengerSettings = append(s.messengerSettings, func(mgr *message.WechatMessenger) {
+			mgr.SetLogger(logger)
+		})
+		return nil
+	}
+}
+
+// WithRetry 设置调用接口的重试次数和间隔
+func WithRetry(count int, interval time.Duration) Option {
+	return WithAPIOptions(api.WithRetry(count, interval))
+}
+
+// WithBaseURL 设置接口地址，覆盖默认的https://api.weixin.qq.com/
+func WithBaseURL(baseURL string) Option {
+	return WithAPIOptions(api.WithBaseURL(baseURL))
+}
+
+// WithBefore 设置请求前hook
+func WithBefore(be api.Before) Option {
+	return WithAPIOptions(api.WithBefore(be))
+}
+
+// WithAfter 设置请求后hook
+func WithAfter(af api.After) Option {
+	return WithAPIOptions(api.WithAfter(af))
+}
+
+// WithAPIOptions 设置WechatAPI配置项
+func WithAPIOptions(opts ...api.Option) Option {
+	return func(s *settings) error {
+		s.apiOptions = append(s.apiOptions, opts...)
+		return nil
+	}
+}
+
+// WithMessenger 设置消息信使，例如安全模式、数据格式、去重及异步处理
+// examples:
+// applet.WithMessenger(func(mgr *message.WechatMessenger) { mgr.SetSecurityMode(message.ModeSecure) })
+func WithMessenger(configure func(*message.WechatMessenger)) Option {
+	return func(s *settings) error {
+		s.messengerSettings = append(s.messengerSettings, configure)
+		return nil
+	}
+}
+
+// WithPay 设置微信支付，使用小程序的appid创建支付客户端
+// examples:
+// applet.WithPay("your mchid", pay.WithMerchantKeyPEM("serial no", pemData), pay.WithAPIv3Key("your apiv3 key"))
+func WithPay(mchID string, opts ...pay.Option) Option {
+	return func(s *settings) error {
+		s.mchID = mchID
+		s.payOptions = append(s.payOptions, opts...)
+		return nil
+	}
+}
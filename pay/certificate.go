@@ -0,0 +1,84 @@
+package pay
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+)
+
+type (
+	// PlatformCertificate 平台证书
+	PlatformCertificate struct {
+		SerialNo           string              `json:"serial_no"`      // 证书序列号
+		EffectiveTime      string              `json:"effective_time"` // 启用时间
+		ExpireTime         string              `json:"expire_time"`    // 过期时间
+		EncryptCertificate *EncryptCertificate `json:"encrypt_certificate"`
+	}
+
+	// EncryptCertificate 加密的平台证书
+	EncryptCertificate struct {
+		Algorithm      string `json:"algorithm"`
+		Nonce          string `json:"nonce"`
+		AssociatedData string `json:"associated_data"`
+		Ciphertext     string `json:"ciphertext"`
+	}
+)
+
+// DownloadCertificates 下载平台证书并添加到客户端，需设置APIv3秘钥
+// 证书会轮换，建议定期调用，使用平台公钥的商户无需调用
+func (c *Client) DownloadCertificates() ([]PlatformCertificate, error) {
+	if len(c.apiV3Key) == 0 {
+		return nil, ErrNoAPIv3Key
+	}
+
+	header, raw, err := c.do("GET", "/v3/certificates", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	respData := &struct {
+		Data []PlatformCertificate `json:"data"`
+	}{}
+	if err := json.Unmarshal(raw, respData); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, item := range respData.Data {
+		if item.EncryptCertificate == nil {
+			continue
+		}
+
+		pemData, err := c.DecryptResource(item.EncryptCertificate.Ciphertext, item.EncryptCertificate.Nonce, item.EncryptCertificate.AssociatedData)
+		if err != nil {
+			return nil, err
+		}
+
+		cert, err := LoadCertificate(pemData)
+		if err != nil {
+			return nil, err
+		}
+
+		key, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("platform certificate is not rsa")
+		}
+		keys[item.SerialNo] = key
+	}
+
+	// 能用APIv3秘钥解密说明证书来自微信支付，再用应答中的证书验证应答签名
+	if key, ok := keys[header.Get("Wechatpay-Serial")]; !ok {
+		err = c.verifyHeader(header, raw)
+	} else if err = checkTimestamp(header); err == nil {
+		err = verifySignature(key, header.Get("Wechatpay-Timestamp"), header.Get("Wechatpay-Nonce"), header.Get("Wechatpay-Signature"), raw)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for serial, key := range keys {
+		c.AddPlatformKey(serial, key)
+	}
+
+	return respData.Data, nil
+}
@@ -0,0 +1,300 @@
+// Package pay 微信支付API v3，小程序JSAPI下单、查询、关单、退款及支付通知
+package pay
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBaseURL 微信支付API v3接口地址
+	DefaultBaseURL = "https://api.mch.weixin.qq.com"
+	// DefaultTimeout 默认的接口超时时间
+	DefaultTimeout = 30 * time.Second
+
+	// authSchema 请求签名的认证类型
+	authSchema = "WECHATPAY2-SHA256-RSA2048"
+)
+
+var (
+	// ErrNoMerchantKey 未设置商户私钥或证书序列号
+	ErrNoMerchantKey = errors.New("merchant private key or serial number is not set")
+	// ErrNoAPIv3Key 未设置APIv3秘钥，无法解密通知及平台证书
+	ErrNoAPIv3Key = errors.New("apiv3 key is not set")
+	// ErrInvalidSignature 微信支付签名校验失败
+	ErrInvalidSignature = errors.New("invalid wechatpay signature")
+	// ErrUnknownSerial 未知的平台证书序列号或公钥ID
+	ErrUnknownSerial = errors.New("unknown wechatpay serial")
+	// ErrExpiredTimestamp 签名时间戳超出允许的时间窗口
+	ErrExpiredTimestamp = errors.New("wechatpay timestamp expired")
+	// ErrNoPlatformKey 未设置平台证书或平台公钥，无法验证应答签名
+	ErrNoPlatformKey = errors.New("platform certificate or public key is not set")
+)
+
+type (
+	// Client 微信支付客户端，与小程序使用同一个appid
+	// examples:
+	// client, err := pay.NewClient("your appid", "your mchid", pay.WithMerchantKey("serial no", key), pay.WithAPIv3Key("your apiv3 key"))
+	// _, err = client.DownloadCertificates() // 或使用pay.WithPlatformPublicKey设置平台公钥，用于验证应答签名
+	Client struct {
+		appID        string
+		mchID        string
+		serialNo     string                    // 商户API证书序列号
+		privateKey   *rsa.PrivateKey           // 商户API私钥
+		apiV3Key     []byte                    // APIv3秘钥
+		platformKeys map[string]*rsa.PublicKey // 平台证书序列号或平台公钥ID -> 公钥
+		skipVerify   bool                      // 不验证应答签名，见WithoutResponseVerification
+		locker       *sync.RWMutex
+		httpClient   *http.Client
+		baseURL      string
+		logger       Logger
+	}
+
+	// Error 微信支付接口返回的错误
+	Error struct {
+		StatusCode int    `json:"-"`
+		Code       string `json:"code"`
+		Message    string `json:"message"`
+	}
+)
+
+// NewClient 新建一个微信支付客户端，必须设置商户私钥及证书序列号
+func NewClient(appID, mchID string, opts ...Option) (*Client, error) {
+	if appID == "" || mchID == "" {
+		return nil, errors.New("appid or mchid is empty")
+	}
+
+	client := &Client{
+		appID:        appID,
+		mchID:        mchID,
+		platformKeys: map[string]*rsa.PublicKey{},
+		locker:       &sync.RWMutex{},
+		httpClient:   &http.Client{Timeout: DefaultTimeout},
+		baseURL:      DefaultBaseURL,
+		logger:       log.New(os.Stderr, "", log.LstdFlags),
+	}
+	for _, opt := range opts {
+		if err := opt(client); err != nil {
+			return nil, err
+		}
+	}
+
+	if client.privateKey == nil || client.serialNo == "" {
+		return nil, ErrNoMerchantKey
+	}
+
+	return client, nil
+}
+
+// AppID 小程序appid
+func (c *Client) AppID() string {
+	return c.appID
+}
+
+// MchID 商户号
+func (c *Client) MchID() string {
+	return c.mchID
+}
+
+// AddPlatformKey 添加平台证书或平台公钥，用于验证应答及通知的签名
+func (c *Client) AddPlatformKey(serial string, key *rsa.PublicKey) {
+	defer c.locker.Unlock()
+	c.locker.Lock()
+
+	c.platformKeys[serial] = key
+}
+
+// Sign 使用商户私钥进行SHA256 with RSA签名，返回base64编码的签名
+func (c *Client) Sign(message string) (string, error) {
+	hashed := sha256.Sum256([]byte(message))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, c.privateKey, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// Verify 使用平台证书验证应答或通知的签名
+func (c *Client) Verify(serial, timestamp, nonce, signature string, body []byte) error {
+	c.locker.RLock()
+	key, ok := c.platformKeys[serial]
+	c.locker.RUnlock()
+	if !ok {
+		return ErrUnknownSerial
+	}
+
+	return verifySignature(key, timestamp, nonce, signature, body)
+}
+
+// verifySignature 使用公钥验证SHA256 with RSA签名
+func verifySignature(key *rsa.PublicKey, timestamp, nonce, signature string, body []byte) error {
+	sign, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	hashed := sha256.Sum256([]byte(timestamp + "\n" + nonce + "\n" + string(body) + "\n"))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], sign); err != nil {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// checkTimestamp 校验应答或通知的签名时间戳，与服务器时间的偏差不能超过NotifyTimeWindow，防止重放
+func checkTimestamp(header http.Header) error {
+	timestamp, err := strconv.ParseInt(header.Get("Wechatpay-Timestamp"), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if offset := time.Since(time.Unix(timestamp, 0)); offset > NotifyTimeWindow || offset < -NotifyTimeWindow {
+		return ErrExpiredTimestamp
+	}
+
+	return nil
+}
+
+// verifyHeader 验证应答或通知头部携带的时间戳及签名
+func (c *Client) verifyHeader(header http.Header, body []byte) error {
+	if err := checkTimestamp(header); err != nil {
+		return err
+	}
+
+	return c.Verify(
+		header.Get("Wechatpay-Serial"),
+		header.Get("Wechatpay-Timestamp"),
+		header.Get("Wechatpay-Nonce"),
+		header.Get("Wechatpay-Signature"),
+		body,
+	)
+}
+
+// hasPlatformKeys 是否已设置平台证书或平台公钥
+func (c *Client) hasPlatformKeys() bool {
+	defer c.locker.RUnlock()
+	c.locker.RLock()
+
+	return len(c.platformKeys) != 0
+}
+
+// authorization 生成请求的Authorization头
+func (c *Client) authorization(method, urlPath string, body []byte) (string, error) {
+	nonce := randomNonce()
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature, err := c.Sign(method + "\n" + urlPath + "\n" + timestamp + "\n" + nonce + "\n" + string(body) + "\n")
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(`%s mchid="%s",nonce_str="%s",signature="%s",timestamp="%s",serial_no="%s"`,
+		authSchema, c.mchID, nonce, signature, timestamp, c.serialNo), nil
+}
+
+// request 调用接口并验证应答签名
+// 未设置平台证书或平台公钥时不发送请求，返回ErrNoPlatformKey，除非设置了WithoutResponseVerification
+func (c *Client) request(method, path string, query url.Values, body interface{}, respData interface{}) error {
+	if !c.skipVerify && !c.hasPlatformKeys() {
+		return ErrNoPlatformKey
+	}
+
+	header, raw, err := c.do(method, path, query, body)
+	if err != nil {
+		return err
+	}
+
+	if !c.skipVerify {
+		if err := c.verifyHeader(header, raw); err != nil {
+			c.logger.Printf("Applet.Pay.Error %s %s %v", method, path, err)
+			return err
+		}
+	}
+
+	if respData != nil && len(raw) != 0 {
+		return json.Unmarshal(raw, respData)
+	}
+
+	return nil
+}
+
+// do 发送签名后的请求，返回应答头及应答体
+func (c *Client) do(method, path string, query url.Values, body interface{}) (http.Header, []byte, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	urlPath := path
+	if len(query) != 0 {
+		urlPath += "?" + query.Encode()
+	}
+
+	authorization, err := c.authorization(method, urlPath, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request, err := http.NewRequest(method, strings.TrimRight(c.baseURL, "/")+urlPath, bytes.NewReader(payload))
+	if err != nil {
+		return nil, nil, err
+	}
+	request.Header.Set("Authorization", authorization)
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("User-Agent", "amazing-gao/applet")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		c.logger.Printf("Applet.Pay.Error %s %s %v", method, path, err)
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+
+	raw, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		respErr := &Error{StatusCode: response.StatusCode}
+		json.Unmarshal(raw, respErr)
+		c.logger.Printf("Applet.Pay.Error %s %s %v", method, path, respErr)
+		return nil, nil, respErr
+	}
+
+	return response.Header, raw, nil
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("wechatpay status:%d code:%s message:%s", err.StatusCode, err.Code, err.Message)
+}
+
+// randomNonce 32位随机字符串
+func randomNonce() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+
+	return strings.ToUpper(hex.EncodeToString(buf))
+}
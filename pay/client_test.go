@@ -0,0 +1,342 @@
+package pay
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAppID     = "wx0000000000000000"
+	testMchID     = "1900000001"
+	testSerial    = "MERCHANT_SERIAL"
+	testPlatform  = "PLATFORM_SERIAL"
+	testAPIv3Key  = "0123456789abcdef0123456789abcdef"
+	testTradeNo   = "order-1"
+	testGCMNonce  = "0123456789ab"
+	testAssociate = "transaction"
+)
+
+var (
+	testKeysOnce    sync.Once
+	testMerchantKey *rsa.PrivateKey
+	testPlatformKey *rsa.PrivateKey
+)
+
+// fakeWechatPay 模拟的微信支付接口，使用平台私钥签名应答
+type fakeWechatPay struct {
+	*httptest.Server
+	t         *testing.T
+	calls     int
+	timestamp time.Time         // 应答签名时间，默认为当前时间
+	serial    string            // 应答的平台证书序列号，默认为testPlatform
+	tamper    bool              // 签名后篡改应答体
+	routes    map[string][]byte // 接口路径 -> 应答体
+}
+
+func testKeys(t *testing.T) (*rsa.PrivateKey, *rsa.PrivateKey) {
+	testKeysOnce.Do(func() {
+		var err error
+		if testMerchantKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			t.Fatal(err)
+		}
+		if testPlatformKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	return testMerchantKey, testPlatformKey
+}
+
+func newFakeWechatPay(t *testing.T) *fakeWechatPay {
+	fake := &fakeWechatPay{t: t, serial: testPlatform, routes: map[string][]byte{}}
+	fake.Server = httptest.NewServer(http.HandlerFunc(fake.serve))
+
+	return fake
+}
+
+func (fake *fakeWechatPay) serve(writer http.ResponseWriter, request *http.Request) {
+	fake.calls++
+	merchantKey, platformKey := testKeys(fake.t)
+
+	// 校验商户的请求签名
+	body, _ := ioutil.ReadAll(request.Body)
+	params := parseAuthorization(request.Header.Get("Authorization"))
+	message := request.Method + "\n" + request.URL.RequestURI() + "\n" + params["timestamp"] + "\n" + params["nonce_str"] + "\n" + string(body) + "\n"
+	if params["mchid"] != testMchID || params["serial_no"] != testSerial {
+		http.Error(writer, `{"code":"SIGN_ERROR"}`, http.StatusUnauthorized)
+		return
+	}
+	sign, _ := base64.StdEncoding.DecodeString(params["signature"])
+	hashed := sha256.Sum256([]byte(message))
+	if rsa.VerifyPKCS1v15(&merchantKey.PublicKey, crypto.SHA256, hashed[:], sign) != nil {
+		http.Error(writer, `{"code":"SIGN_ERROR"}`, http.StatusUnauthorized)
+		return
+	}
+
+	raw, ok := fake.routes[request.URL.Path]
+	if !ok {
+		http.Error(writer, `{"code":"NOT_FOUND"}`, http.StatusNotFound)
+		return
+	}
+
+	timestamp := fake.timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	fake.sign(writer.Header(), platformKey, timestamp, raw)
+	if fake.tamper {
+		raw = append([]byte{}, raw...)
+		raw[len(raw)-2] = 'x'
+	}
+	writer.Write(raw)
+}
+
+// sign 使用平台私钥签名应答或通知
+func (fake *fakeWechatPay) sign(header http.Header, key *rsa.PrivateKey, timestamp time.Time, body []byte) {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	nonce := randomNonce()
+	hashed := sha256.Sum256([]byte(ts + "\n" + nonce + "\n" + string(body) + "\n"))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		fake.t.Fatal(err)
+	}
+
+	header.Set("Wechatpay-Serial", fake.serial)
+	header.Set("Wechatpay-Timestamp", ts)
+	header.Set("Wechatpay-Nonce", nonce)
+	header.Set("Wechatpay-Signature", base64.StdEncoding.EncodeToString(signature))
+}
+
+func parseAuthorization(authorization string) map[string]string {
+	params := map[string]string{}
+	for _, pair := range strings.Split(strings.TrimPrefix(authorization, authSchema+" "), ",") {
+		if index := strings.Index(pair, "="); index > 0 {
+			params[pair[:index]] = strings.Trim(pair[index+1:], `"`)
+		}
+	}
+
+	return params
+}
+
+func newTestClient(t *testing.T, fake *fakeWechatPay, withPlatformKey bool) *Client {
+	merchantKey, platformKey := testKeys(t)
+	opts := []Option{
+		WithMerchantKey(testSerial, merchantKey),
+		WithAPIv3Key(testAPIv3Key),
+		WithBaseURL(fake.URL),
+		WithLogger(log.New(ioutil.Discard, "", 0)),
+	}
+	if withPlatformKey {
+		opts = append(opts, WithPlatformPublicKey(testPlatform, &platformKey.PublicKey))
+	}
+
+	client, err := NewClient(testAppID, testMchID, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+// encryptResource 使用APIv3秘钥加密通知或证书数据
+func encryptResource(t *testing.T, plaintext []byte) string {
+	block, err := aes.NewCipher([]byte(testAPIv3Key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(testGCMNonce))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nil, []byte(testGCMNonce), plaintext, []byte(testAssociate)))
+}
+
+func TestSignRoundTrip(t *testing.T) {
+	fake := newFakeWechatPay(t)
+	defer fake.Close()
+	client := newTestClient(t, fake, true)
+
+	merchantKey, _ := testKeys(t)
+	signature, err := client.Sign("1554208460\nnonce\nbody\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifySignature(&merchantKey.PublicKey, "1554208460", "nonce", signature, []byte("body")); err != nil {
+		t.Fatalf("sign round trip got %v", err)
+	}
+	if err := verifySignature(&merchantKey.PublicKey, "1554208460", "nonce", signature, []byte("other")); err != ErrInvalidSignature {
+		t.Fatalf("tampered message got %v", err)
+	}
+
+	fake.routes["/v3/pay/transactions/out-trade-no/"+testTradeNo] = []byte(`{"out_trade_no":"` + testTradeNo + `","trade_state":"SUCCESS"}`)
+	transaction, err := client.QueryByOutTradeNo(testTradeNo)
+	if err != nil || transaction.OutTradeNo != testTradeNo {
+		t.Fatalf("query got %+v %v", transaction, err)
+	}
+}
+
+func TestRequestRejectsBadResponse(t *testing.T) {
+	fake := newFakeWechatPay(t)
+	defer fake.Close()
+	client := newTestClient(t, fake, true)
+	fake.routes["/v3/pay/transactions/out-trade-no/"+testTradeNo] = []byte(`{"out_trade_no":"` + testTradeNo + `"}`)
+
+	fake.tamper = true
+	if _, err := client.QueryByOutTradeNo(testTradeNo); err != ErrInvalidSignature {
+		t.Fatalf("tampered response got %v", err)
+	}
+
+	fake.tamper, fake.timestamp = false, time.Now().Add(-NotifyTimeWindow-time.Minute)
+	if _, err := client.QueryByOutTradeNo(testTradeNo); err != ErrExpiredTimestamp {
+		t.Fatalf("expired response got %v", err)
+	}
+
+	fake.timestamp, fake.serial = time.Time{}, "OTHER_SERIAL"
+	if _, err := client.QueryByOutTradeNo(testTradeNo); err != ErrUnknownSerial {
+		t.Fatalf("unknown serial got %v", err)
+	}
+}
+
+func TestRequestWithoutPlatformKey(t *testing.T) {
+	fake := newFakeWechatPay(t)
+	defer fake.Close()
+	client := newTestClient(t, fake, false)
+
+	if _, err := client.QueryByOutTradeNo(testTradeNo); err != ErrNoPlatformKey {
+		t.Fatalf("query without platform key got %v", err)
+	}
+	if fake.calls != 0 {
+		t.Fatalf("request was sent %d times without a platform key", fake.calls)
+	}
+}
+
+func TestDownloadCertificates(t *testing.T) {
+	fake := newFakeWechatPay(t)
+	defer fake.Close()
+	client := newTestClient(t, fake, false)
+	_, platformKey := testKeys(t)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Tenpay.com Root CA"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &platformKey.PublicKey, platformKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	raw, _ := json.Marshal(map[string]interface{}{
+		"data": []PlatformCertificate{{
+			SerialNo: testPlatform,
+			EncryptCertificate: &EncryptCertificate{
+				Algorithm:      "AEAD_AES_256_GCM",
+				Nonce:          testGCMNonce,
+				AssociatedData: testAssociate,
+				Ciphertext:     encryptResource(t, certPEM),
+			},
+		}},
+	})
+	fake.routes["/v3/certificates"] = raw
+	fake.routes["/v3/pay/transactions/out-trade-no/"+testTradeNo] = []byte(`{"out_trade_no":"` + testTradeNo + `"}`)
+
+	// 未下载平台证书时可以直接下载，下载后即可调用其他接口
+	certs, err := client.DownloadCertificates()
+	if err != nil || len(certs) != 1 {
+		t.Fatalf("download certificates got %+v %v", certs, err)
+	}
+	if _, err := client.QueryByOutTradeNo(testTradeNo); err != nil {
+		t.Fatalf("query after download got %v", err)
+	}
+
+	fake.timestamp = time.Now().Add(NotifyTimeWindow + time.Minute)
+	if _, err := client.DownloadCertificates(); err != ErrExpiredTimestamp {
+		t.Fatalf("download with expired timestamp got %v", err)
+	}
+}
+
+func TestNotifyHandler(t *testing.T) {
+	fake := newFakeWechatPay(t)
+	defer fake.Close()
+	client := newTestClient(t, fake, true)
+	_, platformKey := testKeys(t)
+
+	paid := ""
+	handler := NewNotifyHandler(client).HandleTransaction(func(notify *Notify, transaction *Transaction) error {
+		paid = transaction.OutTradeNo
+		return nil
+	})
+
+	newNotify := func(ciphertext string) []byte {
+		raw, _ := json.Marshal(&Notify{
+			ID:        "notify-1",
+			EventType: EventTransactionSuccess,
+			Resource: &NotifyResource{
+				Algorithm:      "AEAD_AES_256_GCM",
+				Ciphertext:     ciphertext,
+				AssociatedData: testAssociate,
+				Nonce:          testGCMNonce,
+			},
+		})
+		return raw
+	}
+	post := func(body []byte, timestamp time.Time, tamper bool) int {
+		request := httptest.NewRequest(http.MethodPost, "/pay/notify", nil)
+		fake.sign(request.Header, platformKey, timestamp, body)
+		if tamper {
+			body = []byte(strings.Replace(string(body), "notify-1", "notify-2", 1))
+		}
+		request.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, request)
+		return rec.Code
+	}
+
+	valid := newNotify(encryptResource(t, []byte(`{"out_trade_no":"`+testTradeNo+`"}`)))
+	if code := post(valid, time.Now(), false); code != http.StatusNoContent || paid != testTradeNo {
+		t.Fatalf("valid notify got %d %q", code, paid)
+	}
+	if code := post(valid, time.Now(), true); code != http.StatusUnauthorized {
+		t.Fatalf("tampered notify got %d", code)
+	}
+	if code := post(valid, time.Now().Add(-NotifyTimeWindow-time.Minute), false); code != http.StatusUnauthorized {
+		t.Fatalf("expired notify got %d", code)
+	}
+
+	fake.serial = "OTHER_SERIAL"
+	if code := post(valid, time.Now(), false); code != http.StatusUnauthorized {
+		t.Fatalf("unknown serial notify got %d", code)
+	}
+	fake.serial = testPlatform
+
+	// 密文被篡改，GCM校验失败
+	broken := newNotify(encryptResource(t, []byte("{}"))[:8] + "AAAAAAAAAAAAAAAAAAAAAAAA")
+	if code := post(broken, time.Now(), false); code != http.StatusBadRequest {
+		t.Fatalf("undecryptable notify got %d", code)
+	}
+	if _, err := client.DecryptResource(encryptResource(t, []byte("{}")), testGCMNonce, "other"); err == nil {
+		t.Fatal("decrypt with wrong associated data should fail")
+	}
+}
@@ -0,0 +1,194 @@
+package pay
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// TradeStateSuccess 支付成功
+	TradeStateSuccess = "SUCCESS"
+	// TradeStateRefund 转入退款
+	TradeStateRefund = "REFUND"
+	// TradeStateNotPay 未支付
+	TradeStateNotPay = "NOTPAY"
+	// TradeStateClosed 已关闭
+	TradeStateClosed = "CLOSED"
+	// TradeStateRevoked 已撤销（仅付款码支付）
+	TradeStateRevoked = "REVOKED"
+	// TradeStateUserPaying 用户支付中（仅付款码支付）
+	TradeStateUserPaying = "USERPAYING"
+	// TradeStatePayError 支付失败
+	TradeStatePayError = "PAYERROR"
+)
+
+type (
+	// Amount 订单金额
+	Amount struct {
+		Total         int    `json:"total"`                    // 订单总金额，单位为分
+		PayerTotal    int    `json:"payer_total,omitempty"`    // 用户支付金额，单位为分
+		Currency      string `json:"currency,omitempty"`       // 货币类型，默认CNY
+		PayerCurrency string `json:"payer_currency,omitempty"` // 用户支付币种
+	}
+
+	// Payer 支付者信息
+	Payer struct {
+		OpenID string `json:"openid"` // 用户在小程序appid下的唯一标识
+	}
+
+	// GoodsDetail 单品列表信息
+	GoodsDetail struct {
+		MerchantGoodsID  string `json:"merchant_goods_id"`            // 商户侧商品编码
+		WechatpayGoodsID string `json:"wechatpay_goods_id,omitempty"` // 微信支付商品编码
+		GoodsName        string `json:"goods_name,omitempty"`         // 商品名称
+		Quantity         int    `json:"quantity"`                     // 商品数量
+		UnitPrice        int    `json:"unit_price"`                   // 商品单价，单位为分
+	}
+
+	// OrderDetail 优惠功能
+	OrderDetail struct {
+		CostPrice   int           `json:"cost_price,omitempty"` // 订单原价，单位为分
+		InvoiceID   string        `json:"invoice_id,omitempty"` // 商家小票ID
+		GoodsDetail []GoodsDetail `json:"goods_detail,omitempty"`
+	}
+
+	// StoreInfo 商户门店信息
+	StoreInfo struct {
+		ID       string `json:"id"`                  // 商户侧门店编号
+		Name     string `json:"name,omitempty"`      // 商户侧门店名称
+		AreaCode string `json:"area_code,omitempty"` // 地区编码
+		Address  string `json:"address,omitempty"`   // 详细地址
+	}
+
+	// SceneInfo 场景信息
+	SceneInfo struct {
+		PayerClientIP string     `json:"payer_client_ip"`      // 用户终端IP
+		DeviceID      string     `json:"device_id,omitempty"`  // 商户端设备号
+		StoreInfo     *StoreInfo `json:"store_info,omitempty"` // 商户门店信息
+	}
+
+	// SettleInfo 结算信息
+	SettleInfo struct {
+		ProfitSharing bool `json:"profit_sharing,omitempty"` // 是否指定分账
+	}
+
+	// JSAPIOrder JSAPI下单参数，appid及mchid由客户端填充
+	JSAPIOrder struct {
+		AppID         string       `json:"appid"`
+		MchID         string       `json:"mchid"`
+		Description   string       `json:"description"`              // 商品描述
+		OutTradeNo    string       `json:"out_trade_no"`             // 商户系统内部订单号
+		TimeExpire    string       `json:"time_expire,omitempty"`    // 订单失效时间，RFC 3339格式
+		Attach        string       `json:"attach,omitempty"`         // 附加数据，在查询及支付通知中原样返回
+		NotifyURL     string       `json:"notify_url"`               // 支付通知地址，必须为https
+		GoodsTag      string       `json:"goods_tag,omitempty"`      // 订单优惠标记
+		SupportFapiao bool         `json:"support_fapiao,omitempty"` // 电子发票入口开放标识
+		Amount        *Amount      `json:"amount"`                   // 订单金额
+		Payer         *Payer       `json:"payer"`                    // 支付者
+		Detail        *OrderDetail `json:"detail,omitempty"`         // 优惠功能
+		SceneInfo     *SceneInfo   `json:"scene_info,omitempty"`     // 场景信息
+		SettleInfo    *SettleInfo  `json:"settle_info,omitempty"`    // 结算信息
+	}
+
+	// RespPrepay JSAPI下单结果
+	RespPrepay struct {
+		PrepayID string `json:"prepay_id"` // 预支付交易会话标识，有效期为2小时
+	}
+
+	// RequestPayment 小程序调起支付wx.requestPayment的参数
+	RequestPayment struct {
+		AppID     string `json:"appId"`
+		TimeStamp string `json:"timeStamp"`
+		NonceStr  string `json:"nonceStr"`
+		Package   string `json:"package"`
+		SignType  string `json:"signType"`
+		PaySign   string `json:"paySign"`
+	}
+
+	// Transaction 微信支付订单，查询订单及支付通知的结果
+	Transaction struct {
+		AppID          string     `json:"appid"`
+		MchID          string     `json:"mchid"`
+		OutTradeNo     string     `json:"out_trade_no"`     // 商户订单号
+		TransactionID  string     `json:"transaction_id"`   // 微信支付订单号
+		TradeType      string     `json:"trade_type"`       // 交易类型，JSAPI
+		TradeState     string     `json:"trade_state"`      // 交易状态，见TradeState*
+		TradeStateDesc string     `json:"trade_state_desc"` // 交易状态描述
+		BankType       string     `json:"bank_type"`        // 付款银行
+		Attach         string     `json:"attach"`           // 附加数据
+		SuccessTime    string     `json:"success_time"`     // 支付完成时间，RFC 3339格式
+		Payer          *Payer     `json:"payer"`
+		Amount         *Amount    `json:"amount"`
+		SceneInfo      *SceneInfo `json:"scene_info"`
+	}
+)
+
+// Prepay JSAPI下单，获取预支付交易会话标识
+func (c *Client) Prepay(order *JSAPIOrder) (*RespPrepay, error) {
+	body := *order
+	body.AppID = c.appID
+	body.MchID = c.mchID
+
+	respData := &RespPrepay{}
+	if err := c.request("POST", "/v3/pay/transactions/jsapi", nil, &body, respData); err != nil {
+		return nil, err
+	}
+
+	return respData, nil
+}
+
+// RequestPayment 生成小程序调起支付wx.requestPayment的参数
+func (c *Client) RequestPayment(prepayID string) (*RequestPayment, error) {
+	params := &RequestPayment{
+		AppID:     c.appID,
+		TimeStamp: strconv.FormatInt(time.Now().Unix(), 10),
+		NonceStr:  randomNonce(),
+		Package:   "prepay_id=" + prepayID,
+		SignType:  "RSA",
+	}
+
+	sign, err := c.Sign(params.AppID + "\n" + params.TimeStamp + "\n" + params.NonceStr + "\n" + params.Package + "\n")
+	if err != nil {
+		return nil, err
+	}
+	params.PaySign = sign
+
+	return params, nil
+}
+
+// PrepayWithRequestPayment JSAPI下单并生成wx.requestPayment的参数
+func (c *Client) PrepayWithRequestPayment(order *JSAPIOrder) (*RequestPayment, error) {
+	respData, err := c.Prepay(order)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.RequestPayment(respData.PrepayID)
+}
+
+// QueryByTransactionID 微信支付订单号查询订单
+func (c *Client) QueryByTransactionID(transactionID string) (*Transaction, error) {
+	return c.query("/v3/pay/transactions/id/" + url.PathEscape(transactionID))
+}
+
+// QueryByOutTradeNo 商户订单号查询订单
+func (c *Client) QueryByOutTradeNo(outTradeNo string) (*Transaction, error) {
+	return c.query("/v3/pay/transactions/out-trade-no/" + url.PathEscape(outTradeNo))
+}
+
+// Close 关闭订单，用户支付失败或超时未支付时调用，下单后需间隔5分钟
+func (c *Client) Close(outTradeNo string) error {
+	return c.request("POST", "/v3/pay/transactions/out-trade-no/"+url.PathEscape(outTradeNo)+"/close", nil, map[string]string{
+		"mchid": c.mchID,
+	}, nil)
+}
+
+func (c *Client) query(path string) (*Transaction, error) {
+	respData := &Transaction{}
+	if err := c.request("GET", path, url.Values{"mchid": {c.mchID}}, nil, respData); err != nil {
+		return nil, err
+	}
+
+	return respData, nil
+}
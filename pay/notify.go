@@ -0,0 +1,232 @@
+package pay
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// EventTransactionSuccess 支付成功通知
+	EventTransactionSuccess = "TRANSACTION.SUCCESS"
+	// EventRefundSuccess 退款成功通知
+	EventRefundSuccess = "REFUND.SUCCESS"
+	// EventRefundAbnormal 退款异常通知
+	EventRefundAbnormal = "REFUND.ABNORMAL"
+	// EventRefundClosed 退款关闭通知
+	EventRefundClosed = "REFUND.CLOSED"
+
+	// NotifyTimeWindow 应答及通知的签名时间戳与服务器时间允许的最大偏差
+	NotifyTimeWindow = 5 * time.Minute
+	// maxNotifyBodySize 通知消息体的最大长度
+	maxNotifyBodySize = 1 << 20
+)
+
+type (
+	// Notify 微信支付通知，Plaintext为解密后的资源数据
+	Notify struct {
+		ID           string          `json:"id"`            // 通知ID
+		CreateTime   string          `json:"create_time"`   // 通知创建时间，RFC 3339格式
+		EventType    string          `json:"event_type"`    // 通知类型，见Event*
+		ResourceType string          `json:"resource_type"` // 通知数据类型，encrypt-resource
+		Summary      string          `json:"summary"`       // 回调摘要
+		Resource     *NotifyResource `json:"resource"`      // 通知数据
+		Plaintext    []byte          `json:"-"`
+	}
+
+	// NotifyResource 加密的通知数据
+	NotifyResource struct {
+		Algorithm      string `json:"algorithm"`       // 加密算法，AEAD_AES_256_GCM
+		Ciphertext     string `json:"ciphertext"`      // base64编码的密文
+		AssociatedData string `json:"associated_data"` // 附加数据
+		OriginalType   string `json:"original_type"`   // 原始回调类型，transaction或refund
+		Nonce          string `json:"nonce"`           // 加密使用的随机串
+	}
+
+	// RefundNotify 退款结果通知的资源数据
+	RefundNotify struct {
+		MchID               string        `json:"mchid"`
+		OutTradeNo          string        `json:"out_trade_no"`          // 商户订单号
+		TransactionID       string        `json:"transaction_id"`        // 微信支付订单号
+		OutRefundNo         string        `json:"out_refund_no"`         // 商户退款单号
+		RefundID            string        `json:"refund_id"`             // 微信支付退款单号
+		RefundStatus        string        `json:"refund_status"`         // 退款状态，见RefundStatus*
+		SuccessTime         string        `json:"success_time"`          // 退款成功时间
+		UserReceivedAccount string        `json:"user_received_account"` // 退款入账账户
+		Amount              *RefundAmount `json:"amount"`                // 金额信息
+	}
+
+	// TransactionHandler 支付成功通知处理器，返回错误时微信支付会重新通知
+	TransactionHandler func(*Notify, *Transaction) error
+
+	// RefundHandler 退款结果通知处理器，返回错误时微信支付会重新通知
+	RefundHandler func(*Notify, *RefundNotify) error
+
+	// NotifyHandler 微信支付通知处理器，实现http.Handler，可直接挂载到路由
+	// examples:
+	// http.Handle("/pay/notify", pay.NewNotifyHandler(client).HandleTransaction(onPaid).HandleRefund(onRefund))
+	NotifyHandler struct {
+		client             *Client
+		transactionHandler TransactionHandler
+		refundHandler      RefundHandler
+	}
+
+	notifyResult struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+)
+
+// ParseNotify 验证通知的签名及时间戳，并解密通知数据
+func (c *Client) ParseNotify(request *http.Request) (*Notify, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(request.Body, maxNotifyBodySize))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.verifyHeader(request.Header, body); err != nil {
+		return nil, err
+	}
+
+	notify := &Notify{}
+	if err := json.Unmarshal(body, notify); err != nil {
+		return nil, err
+	}
+	if notify.Resource == nil {
+		return nil, errors.New("notify resource is empty")
+	}
+
+	if notify.Plaintext, err = c.DecryptResource(notify.Resource.Ciphertext, notify.Resource.Nonce, notify.Resource.AssociatedData); err != nil {
+		return nil, err
+	}
+
+	return notify, nil
+}
+
+// DecryptResource 使用APIv3秘钥解密AEAD_AES_256_GCM加密的数据
+func (c *Client) DecryptResource(ciphertext, nonce, associatedData string) ([]byte, error) {
+	if len(c.apiV3Key) == 0 {
+		return nil, ErrNoAPIv3Key
+	}
+
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(c.apiV3Key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(nonce))
+	if err != nil {
+		return nil, err
+	}
+
+	return gcm.Open(nil, []byte(nonce), data, []byte(associatedData))
+}
+
+// Transaction 解析支付成功通知的订单数据
+func (notify *Notify) Transaction() (*Transaction, error) {
+	transaction := &Transaction{}
+	if err := json.Unmarshal(notify.Plaintext, transaction); err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// Refund 解析退款结果通知的退款数据
+func (notify *Notify) Refund() (*RefundNotify, error) {
+	refund := &RefundNotify{}
+	if err := json.Unmarshal(notify.Plaintext, refund); err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+// NewNotifyHandler 新建一个微信支付通知处理器
+func NewNotifyHandler(client *Client) *NotifyHandler {
+	return &NotifyHandler{client: client}
+}
+
+// HandleTransaction 注册支付成功通知处理器
+func (handler *NotifyHandler) HandleTransaction(transactionHandler TransactionHandler) *NotifyHandler {
+	handler.transactionHandler = transactionHandler
+
+	return handler
+}
+
+// HandleRefund 注册退款结果通知处理器
+func (handler *NotifyHandler) HandleRefund(refundHandler RefundHandler) *NotifyHandler {
+	handler.refundHandler = refundHandler
+
+	return handler
+}
+
+// ServeHTTP 实现http.Handler
+// 处理成功响应204，签名校验失败响应401，处理器返回错误时响应500，微信支付会重新通知
+// 未注册处理器的通知类型直接响应204
+func (handler *NotifyHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		handler.fail(writer, http.StatusMethodNotAllowed, errors.New(http.StatusText(http.StatusMethodNotAllowed)))
+		return
+	}
+
+	notify, err := handler.client.ParseNotify(request)
+	if err != nil {
+		status := http.StatusBadRequest
+		if err == ErrInvalidSignature || err == ErrUnknownSerial || err == ErrExpiredTimestamp {
+			status = http.StatusUnauthorized
+		}
+		handler.fail(writer, status, err)
+		return
+	}
+
+	if err := handler.dispatch(notify); err != nil {
+		handler.fail(writer, http.StatusInternalServerError, err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// dispatch 按通知类型调用处理器
+func (handler *NotifyHandler) dispatch(notify *Notify) error {
+	if strings.HasPrefix(notify.EventType, "TRANSACTION.") && handler.transactionHandler != nil {
+		transaction, err := notify.Transaction()
+		if err != nil {
+			return err
+		}
+		return handler.transactionHandler(notify, transaction)
+	}
+
+	if strings.HasPrefix(notify.EventType, "REFUND.") && handler.refundHandler != nil {
+		refund, err := notify.Refund()
+		if err != nil {
+			return err
+		}
+		return handler.refundHandler(notify, refund)
+	}
+
+	return nil
+}
+
+func (handler *NotifyHandler) fail(writer http.ResponseWriter, status int, err error) {
+	handler.client.logger.Printf("Applet.Pay.Notify.Error %v", err)
+
+	// 错误详情只记录日志，不返回给调用方
+	body, _ := json.Marshal(&notifyResult{Code: "FAIL", Message: "失败"})
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	writer.Write(body)
+}
@@ -0,0 +1,180 @@
+package pay
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/amazing-gao/applet/internal/logger"
+)
+
+type (
+	// Option 微信支付客户端配置项
+	Option func(*Client) error
+
+	// Logger 日志接口，兼容标准库*log.Logger
+	Logger = logger.Logger
+)
+
+// WithMerchantKey 设置商户API私钥及商户API证书序列号，用于请求签名
+func WithMerchantKey(serialNo string, key *rsa.PrivateKey) Option {
+	return func(c *Client) error {
+		if serialNo == "" || key == nil {
+			return ErrNoMerchantKey
+		}
+		c.serialNo = serialNo
+		c.privateKey = key
+		return nil
+	}
+}
+
+// WithMerchantKeyPEM 设置PEM格式的商户API私钥，即apiclient_key.pem的内容
+func WithMerchantKeyPEM(serialNo string, pemData []byte) Option {
+	return func(c *Client) error {
+		key, err := LoadPrivateKey(pemData)
+		if err != nil {
+			return err
+		}
+		return WithMerchantKey(serialNo, key)(c)
+	}
+}
+
+// WithAPIv3Key 设置APIv3秘钥，用于解密支付通知及平台证书，应为32个字符
+func WithAPIv3Key(apiV3Key string) Option {
+	return func(c *Client) error {
+		if len(apiV3Key) != 32 {
+			return errors.New("apiv3 key must be 32 bytes")
+		}
+		c.apiV3Key = []byte(apiV3Key)
+		return nil
+	}
+}
+
+// WithPlatformCertificate 设置平台证书，用于验证应答及通知的签名
+func WithPlatformCertificate(cert *x509.Certificate) Option {
+	return func(c *Client) error {
+		key, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("platform certificate is not rsa")
+		}
+		c.platformKeys[certificateSerial(cert)] = key
+		return nil
+	}
+}
+
+// WithPlatformPublicKey 设置平台公钥及公钥ID(PUB_KEY_ID_开头)，用于验证应答及通知的签名
+func WithPlatformPublicKey(keyID string, key *rsa.PublicKey) Option {
+	return func(c *Client) error {
+		if keyID == "" || key == nil {
+			return errors.New("platform public key or id is empty")
+		}
+		c.platformKeys[keyID] = key
+		return nil
+	}
+}
+
+// WithoutResponseVerification 不验证接口应答的签名，仅用于测试环境
+// 默认未设置平台证书或平台公钥时接口调用返回ErrNoPlatformKey，支付通知始终验证签名
+func WithoutResponseVerification() Option {
+	return func(c *Client) error {
+		c.skipVerify = true
+		return nil
+	}
+}
+
+// WithHTTPClient 设置http客户端，可用于配置超时
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) error {
+		if client == nil {
+			return errors.New("http client is nil")
+		}
+		c.httpClient = client
+		return nil
+	}
+}
+
+// WithBaseURL 设置接口地址，覆盖默认的https://api.mch.weixin.qq.com
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) error {
+		if _, err := url.ParseRequestURI(baseURL); err != nil {
+			return fmt.Errorf("invalid base url %q: %v", baseURL, err)
+		}
+		c.baseURL = baseURL
+		return nil
+	}
+}
+
+// WithLogger 设置日志
+func WithLogger(logger Logger) Option {
+	return func(c *Client) error {
+		if logger == nil {
+			return errors.New("logger is nil")
+		}
+		c.logger = logger
+		return nil
+	}
+}
+
+// LoadPrivateKey 解析PEM格式的RSA私钥，支持PKCS#8及PKCS#1
+func LoadPrivateKey(pemData []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("invalid private key pem")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not rsa")
+	}
+
+	return rsaKey, nil
+}
+
+// LoadCertificate 解析PEM格式的证书
+func LoadCertificate(pemData []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("invalid certificate pem")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// LoadPublicKey 解析PEM格式的RSA公钥，即平台公钥pub_key.pem的内容
+func LoadPublicKey(pemData []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("invalid public key pem")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not rsa")
+	}
+
+	return rsaKey, nil
+}
+
+// certificateSerial 证书序列号，微信支付使用大写的十六进制
+func certificateSerial(cert *x509.Certificate) string {
+	return strings.ToUpper(cert.SerialNumber.Text(16))
+}
@@ -0,0 +1,87 @@
+package pay
+
+import (
+	"net/url"
+)
+
+const (
+	// RefundStatusSuccess 退款成功
+	RefundStatusSuccess = "SUCCESS"
+	// RefundStatusClosed 退款关闭
+	RefundStatusClosed = "CLOSED"
+	// RefundStatusProcessing 退款处理中
+	RefundStatusProcessing = "PROCESSING"
+	// RefundStatusAbnormal 退款异常
+	RefundStatusAbnormal = "ABNORMAL"
+)
+
+type (
+	// RefundAmount 退款金额
+	RefundAmount struct {
+		Refund           int    `json:"refund"`                      // 退款金额，单位为分
+		Total            int    `json:"total"`                       // 原订单金额，单位为分
+		Currency         string `json:"currency"`                    // 退款币种，目前只支持CNY
+		PayerTotal       int    `json:"payer_total,omitempty"`       // 用户支付金额
+		PayerRefund      int    `json:"payer_refund,omitempty"`      // 用户退款金额
+		SettlementRefund int    `json:"settlement_refund,omitempty"` // 应结退款金额
+		SettlementTotal  int    `json:"settlement_total,omitempty"`  // 应结订单金额
+		DiscountRefund   int    `json:"discount_refund,omitempty"`   // 优惠退款金额
+	}
+
+	// RefundGoods 退款商品
+	RefundGoods struct {
+		MerchantGoodsID  string `json:"merchant_goods_id"`            // 商户侧商品编码
+		WechatpayGoodsID string `json:"wechatpay_goods_id,omitempty"` // 微信支付商品编码
+		GoodsName        string `json:"goods_name,omitempty"`         // 商品名称
+		UnitPrice        int    `json:"unit_price"`                   // 商品单价，单位为分
+		RefundAmount     int    `json:"refund_amount"`                // 商品退款金额，单位为分
+		RefundQuantity   int    `json:"refund_quantity"`              // 商品退货数量
+	}
+
+	// RefundRequest 申请退款参数，微信支付订单号和商户订单号二选一
+	RefundRequest struct {
+		TransactionID string        `json:"transaction_id,omitempty"` // 微信支付订单号
+		OutTradeNo    string        `json:"out_trade_no,omitempty"`   // 商户订单号
+		OutRefundNo   string        `json:"out_refund_no"`            // 商户系统内部的退款单号
+		Reason        string        `json:"reason,omitempty"`         // 退款原因，会在下发给用户的退款消息中体现
+		NotifyURL     string        `json:"notify_url,omitempty"`     // 退款结果通知地址
+		FundsAccount  string        `json:"funds_account,omitempty"`  // 退款资金来源，AVAILABLE可用余额账户
+		Amount        *RefundAmount `json:"amount"`                   // 金额信息
+		GoodsDetail   []RefundGoods `json:"goods_detail,omitempty"`   // 退款商品
+	}
+
+	// Refund 退款单，申请退款及查询退款的结果
+	Refund struct {
+		RefundID            string        `json:"refund_id"`             // 微信支付退款单号
+		OutRefundNo         string        `json:"out_refund_no"`         // 商户退款单号
+		TransactionID       string        `json:"transaction_id"`        // 微信支付订单号
+		OutTradeNo          string        `json:"out_trade_no"`          // 商户订单号
+		Channel             string        `json:"channel"`               // 退款渠道，ORIGINAL原路退款
+		UserReceivedAccount string        `json:"user_received_account"` // 退款入账账户
+		SuccessTime         string        `json:"success_time"`          // 退款成功时间，RFC 3339格式
+		CreateTime          string        `json:"create_time"`           // 退款创建时间，RFC 3339格式
+		Status              string        `json:"status"`                // 退款状态，见RefundStatus*
+		FundsAccount        string        `json:"funds_account"`         // 资金账户
+		Amount              *RefundAmount `json:"amount"`                // 金额信息
+	}
+)
+
+// Refund 申请退款，交易时间超过一年的订单无法提交退款
+func (c *Client) Refund(refund *RefundRequest) (*Refund, error) {
+	respData := &Refund{}
+	if err := c.request("POST", "/v3/refund/domestic/refunds", nil, refund, respData); err != nil {
+		return nil, err
+	}
+
+	return respData, nil
+}
+
+// QueryRefund 查询单笔退款
+func (c *Client) QueryRefund(outRefundNo string) (*Refund, error) {
+	respData := &Refund{}
+	if err := c.request("GET", "/v3/refund/domestic/refunds/"+url.PathEscape(outRefundNo), nil, nil, respData); err != nil {
+		return nil, err
+	}
+
+	return respData, nil
+}
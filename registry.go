@@ -0,0 +1,268 @@
+package applet
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sync"
+
+	"github.com/amazing-gao/applet/api"
+	"github.com/amazing-gao/applet/message"
+)
+
+type (
+	// AppletConfig 小程序配置
+	AppletConfig struct {
+		AppID              string `json:"app_id" yaml:"app_id" toml:"app_id" env:"APPLET_APP_ID"`                                                             // 小程序id
+		AppKey             string `json:"app_key" yaml:"app_key" toml:"app_key" env:"APPLET_APP_KEY"`                                                         // 小程序秘钥
+		AppKeyFile         string `json:"app_key_file" yaml:"app_key_file" toml:"app_key_file" env:"APPLET_APP_KEY_FILE"`                                     // 小程序秘钥文件，优先于AppKey
+		AppToken           string `json:"app_token" yaml:"app_token" toml:"app_token" env:"APPLET_APP_TOKEN"`                                                 // 小程序token
+		AppTokenFile       string `json:"app_token_file" yaml:"app_token_file" toml:"app_token_file" env:"APPLET_APP_TOKEN_FILE"`                             // 小程序token文件，优先于AppToken
+		EncodingAESKey     string `json:"encoding_aes_key" yaml:"encoding_aes_key" toml:"encoding_aes_key" env:"APPLET_ENCODING_AES_KEY"`                     // 小程序加密秘钥
+		EncodingAESKeyFile string `json:"encoding_aes_key_file" yaml:"encoding_aes_key_file" toml:"encoding_aes_key_file" env:"APPLET_ENCODING_AES_KEY_FILE"` // 小程序加密秘钥文件，优先于EncodingAESKey
+		UserName           string `json:"user_name" yaml:"user_name" toml:"user_name" env:"APPLET_USER_NAME"`                                                 // 小程序原始ID，即推送消息的ToUserName
+		BaseURL            string `json:"base_url" yaml:"base_url" toml:"base_url" env:"APPLET_BASE_URL"`                                                     // 接口地址，默认https://api.weixin.qq.com/
+	}
+
+	// AppletSource 小程序配置来源
+	AppletSource interface {
+		Load() ([]*AppletConfig, error)
+	}
+
+	// AppletSourceFunc 函数形式的小程序配置来源
+	AppletSourceFunc func() ([]*AppletConfig, error)
+
+	// TokenStoreFactory 为每个小程序生成token存储器
+	TokenStoreFactory func(appID string) api.WechatTokenStore
+
+	// AppletHook 小程序实例创建后、加入注册表前的回调，可用于注册消息处理器
+	// 回调在注册表锁外执行，可以调用注册表的方法
+	AppletHook func(*Applet, *AppletConfig)
+
+	// AppletRegistry 多小程序注册表
+	// 按appID缓存小程序实例，并按路径中的appID或消息的ToUserName路由消息推送
+	AppletRegistry struct {
+		source            AppletSource
+		tokenStoreFactory TokenStoreFactory
+		onCreate          AppletHook
+		opts              []Option
+		applets           map[string]*Applet       // appID -> 小程序
+		configs           map[string]*AppletConfig // appID -> 小程序配置
+		userNames         map[string]string        // 原始ID -> appID
+		locker            *sync.RWMutex
+	}
+)
+
+// Load 加载小程序配置
+func (fn AppletSourceFunc) Load() ([]*AppletConfig, error) {
+	return fn()
+}
+
+// NewAppletRegistry 新建一个多小程序注册表，opts会应用到每个小程序
+func NewAppletRegistry(source AppletSource, tokenStoreFactory TokenStoreFactory, onCreate AppletHook, opts ...Option) *AppletRegistry {
+	return &AppletRegistry{
+		source:            source,
+		tokenStoreFactory: tokenStoreFactory,
+		onCreate:          onCreate,
+		opts:              opts,
+		applets:           map[string]*Applet{},
+		configs:           map[string]*AppletConfig{},
+		userNames:         map[string]string{},
+		locker:            &sync.RWMutex{},
+	}
+}
+
+// Reload 从配置来源重新加载小程序
+// 新增的小程序会被创建，配置变更的小程序会被重建，不再存在的小程序会被移除
+// 被重建和移除的小程序会在后台停止其消息信使
+// 配置错误的小程序会被跳过，返回第一个错误
+func (reg *AppletRegistry) Reload() error {
+	configs, err := reg.source.Load()
+	if err != nil {
+		return err
+	}
+
+	loaded := map[string]bool{}
+	for _, cfg := range configs {
+		loaded[cfg.AppID] = true
+		if _, addErr := reg.Add(cfg); addErr != nil && err == nil {
+			err = addErr
+		}
+	}
+
+	for _, appID := range reg.AppIDs() {
+		if !loaded[appID] {
+			// Remove会停止被移除小程序的消息信使
+			reg.Remove(appID)
+		}
+	}
+
+	return err
+}
+
+// Add 添加小程序，配置未变更时返回已有的实例
+// 被替换的小程序会在后台停止其消息信使，onCreate在注册表锁外调用
+func (reg *AppletRegistry) Add(cfg *AppletConfig) (*Applet, error) {
+	if applet := reg.unchanged(cfg); applet != nil {
+		return applet, nil
+	}
+
+	opts := reg.opts
+	if reg.tokenStoreFactory != nil {
+		opts = append([]Option{WithTokenStore(reg.tokenStoreFactory(cfg.AppID))}, opts...)
+	}
+
+	applet, err := NewAppletFromConfig(cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if reg.onCreate != nil {
+		reg.onCreate(applet, cfg)
+	}
+
+	reg.locker.Lock()
+	// 并发添加相同配置时保留先添加的实例
+	if existing := reg.unchangedLocked(cfg); existing != nil {
+		reg.locker.Unlock()
+		shutdown(applet)
+		return existing, nil
+	}
+
+	old := reg.remove(cfg.AppID)
+	copied := *cfg
+	reg.applets[cfg.AppID] = applet
+	reg.configs[cfg.AppID] = &copied
+	if cfg.UserName != "" {
+		reg.userNames[cfg.UserName] = cfg.AppID
+	}
+	reg.locker.Unlock()
+
+	shutdown(old)
+
+	return applet, nil
+}
+
+// Remove 移除小程序，并在后台停止其消息信使，返回被移除的实例
+func (reg *AppletRegistry) Remove(appID string) *Applet {
+	reg.locker.Lock()
+	applet := reg.remove(appID)
+	reg.locker.Unlock()
+
+	shutdown(applet)
+
+	return applet
+}
+
+// Get 按appID获取小程序
+func (reg *AppletRegistry) Get(appID string) *Applet {
+	defer reg.locker.RUnlock()
+	reg.locker.RLock()
+
+	return reg.applets[appID]
+}
+
+// GetByUserName 按原始ID获取小程序
+func (reg *AppletRegistry) GetByUserName(userName string) *Applet {
+	defer reg.locker.RUnlock()
+	reg.locker.RLock()
+
+	return reg.applets[reg.userNames[userName]]
+}
+
+// AppIDs 获取已注册的全部appID
+func (reg *AppletRegistry) AppIDs() []string {
+	defer reg.locker.RUnlock()
+	reg.locker.RLock()
+
+	appIDs := make([]string, 0, len(reg.applets))
+	for appID := range reg.applets {
+		appIDs = append(appIDs, appID)
+	}
+
+	return appIDs
+}
+
+// ServeHTTP 将消息推送路由到对应小程序的消息信使
+// 优先 路径的最后一段作为appID，例如 /wechat/push/{appid}
+// 再次 消息的ToUserName作为原始ID
+func (reg *AppletRegistry) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	applet := reg.Get(path.Base(request.URL.Path))
+
+	if applet == nil && request.Method == http.MethodPost {
+		userName, err := message.PeekToUserName(request, reg.maxBodySize())
+		if err == message.ErrBodyTooLarge {
+			http.Error(writer, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err == nil {
+			applet = reg.GetByUserName(userName)
+		}
+	}
+
+	if applet == nil {
+		http.NotFound(writer, request)
+		return
+	}
+
+	applet.Messager.ServeHTTP(writer, request)
+}
+
+func (reg *AppletRegistry) remove(appID string) *Applet {
+	applet := reg.applets[appID]
+	if cfg, ok := reg.configs[appID]; ok && reg.userNames[cfg.UserName] == appID {
+		delete(reg.userNames, cfg.UserName)
+	}
+
+	delete(reg.applets, appID)
+	delete(reg.configs, appID)
+
+	return applet
+}
+
+// unchanged 配置未变更时返回已有的实例
+func (reg *AppletRegistry) unchanged(cfg *AppletConfig) *Applet {
+	defer reg.locker.RUnlock()
+	reg.locker.RLock()
+
+	return reg.unchangedLocked(cfg)
+}
+
+func (reg *AppletRegistry) unchangedLocked(cfg *AppletConfig) *Applet {
+	if old, ok := reg.configs[cfg.AppID]; ok && *old == *cfg {
+		return reg.applets[cfg.AppID]
+	}
+
+	return nil
+}
+
+// maxBodySize 按ToUserName路由时读取消息体的最大长度，取各小程序消息信使配置的最大值
+// 路由后由对应的消息信使按自身配置再次校验
+func (reg *AppletRegistry) maxBodySize() int64 {
+	defer reg.locker.RUnlock()
+	reg.locker.RLock()
+
+	var size int64
+	for _, applet := range reg.applets {
+		limit := applet.Messager.MaxBodySize()
+		if limit <= 0 {
+			return 0
+		}
+		if limit > size {
+			size = limit
+		}
+	}
+
+	if size == 0 {
+		size = message.DefaultMaxBodySize
+	}
+
+	return size
+}
+
+// shutdown 在后台停止小程序的消息信使，等待队列中的消息处理完毕
+func shutdown(applet *Applet) {
+	if applet != nil {
+		go applet.Messager.Shutdown(context.Background())
+	}
+}
@@ -0,0 +1,180 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/amazing-gao/applet/crypto"
+	"github.com/amazing-gao/applet/internal/logger"
+)
+
+const (
+	// maxLoginBodySize 登录请求体的最大长度
+	maxLoginBodySize = 64 << 10
+)
+
+type (
+	// Issuer 会话签发器，*Service为默认实现，可替换为自定义的会话体系
+	Issuer interface {
+		Login(code string) (token string, session *Session, err error)
+		Verify(token string) (*Session, error)
+	}
+
+	// ProfileSyncer 登录时同步用户资料，例如保存昵称、头像
+	ProfileSyncer func(ctx context.Context, session *Session, user *crypto.UserInfo) error
+
+	// LoginRequest 登录请求，用户资料相关字段为wx.getUserInfo的返回，可选
+	LoginRequest struct {
+		Code          string `json:"code"`
+		RawData       string `json:"rawData"`
+		Signature     string `json:"signature"`
+		EncryptedData string `json:"encryptedData"`
+		IV            string `json:"iv"`
+	}
+
+	// LoginResponse 登录响应
+	LoginResponse struct {
+		Token   string `json:"token"`
+		OpenID  string `json:"openid"`
+		UnionID string `json:"unionid,omitempty"`
+	}
+
+	// LoginHandler 小程序登录接口 POST {code} -> Code2Session -> 签发会话令牌
+	LoginHandler struct {
+		issuer Issuer
+		crypto *crypto.WechatCrypto
+		syncer ProfileSyncer
+		logger Logger
+	}
+
+	// Logger 日志接口，兼容标准库*log.Logger
+	Logger = logger.Logger
+
+	contextKey struct{}
+)
+
+// NewLoginHandler 新建一个登录接口
+// crypto和syncer不为空且请求携带用户资料时，解密并同步用户资料
+func NewLoginHandler(issuer Issuer, crypto *crypto.WechatCrypto, syncer ProfileSyncer) *LoginHandler {
+	return &LoginHandler{
+		issuer: issuer,
+		crypto: crypto,
+		syncer: syncer,
+		logger: log.New(os.Stderr, "", log.LstdFlags),
+	}
+}
+
+// SetLogger 设置日志
+func (handler *LoginHandler) SetLogger(logger Logger) *LoginHandler {
+	handler.logger = logger
+
+	return handler
+}
+
+// ServeHTTP 处理登录请求，失败时只响应状态码，不暴露内部错误
+// code无效等登录失败响应401，Issuer返回*UpstreamError时响应502
+func (handler *LoginHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writeStatus(writer, http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := &LoginRequest{}
+	if err := json.NewDecoder(io.LimitReader(request.Body, maxLoginBodySize)).Decode(req); err != nil || req.Code == "" {
+		writeStatus(writer, http.StatusBadRequest)
+		return
+	}
+
+	token, session, err := handler.issuer.Login(req.Code)
+	if err != nil {
+		handler.logger.Printf("Applet.Login.Error %v", err)
+		// 微信接口不可用时客户端可重试，不应视为code无效
+		if _, ok := err.(*UpstreamError); ok {
+			writeStatus(writer, http.StatusBadGateway)
+		} else {
+			writeStatus(writer, http.StatusUnauthorized)
+		}
+		return
+	}
+
+	if handler.crypto != nil && handler.syncer != nil && req.EncryptedData != "" {
+		user, err := handler.crypto.DecryptUserInfo(session.SessionKey, req.EncryptedData, req.RawData, req.IV, req.Signature)
+		if err == nil {
+			err = handler.syncer(request.Context(), session, user)
+		}
+		// 资料同步失败不影响登录
+		if err != nil {
+			handler.logger.Printf("Applet.Login.SyncProfile.Error openid:%s %v", session.OpenID, err)
+		}
+	}
+
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(writer).Encode(&LoginResponse{
+		Token:   token,
+		OpenID:  session.OpenID,
+		UnionID: session.UnionID,
+	})
+}
+
+// Middleware 鉴权中间件，校验Authorization: Bearer <token>，并将会话写入context
+// 校验失败时响应401
+func Middleware(issuer Issuer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			token, ok := bearerToken(request.Header.Get("Authorization"))
+			if !ok {
+				writeStatus(writer, http.StatusUnauthorized)
+				return
+			}
+
+			session, err := issuer.Verify(token)
+			if err != nil {
+				writeStatus(writer, http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(writer, request.WithContext(NewContext(request.Context(), session)))
+		})
+	}
+}
+
+// bearerToken 解析Authorization头，要求以"Bearer "开头，前缀不区分大小写
+func bearerToken(authorization string) (string, bool) {
+	const prefix = "Bearer "
+	if len(authorization) <= len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	token := strings.TrimSpace(authorization[len(prefix):])
+
+	return token, token != ""
+}
+
+// NewContext 将会话写入context
+func NewContext(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, contextKey{}, session)
+}
+
+// FromContext 从context获取当前会话
+func FromContext(ctx context.Context) (*Session, bool) {
+	session, ok := ctx.Value(contextKey{}).(*Session)
+	return session, ok
+}
+
+// OpenIDFromContext 从context获取当前用户的openid，未登录时为空
+func OpenIDFromContext(ctx context.Context) string {
+	if session, ok := FromContext(ctx); ok {
+		return session.OpenID
+	}
+
+	return ""
+}
+
+func writeStatus(writer http.ResponseWriter, status int) {
+	http.Error(writer, http.StatusText(status), status)
+}
@@ -0,0 +1,141 @@
+package session
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amazing-gao/applet/api"
+	"github.com/amazing-gao/applet/applettest"
+)
+
+func newLoginHandler(t *testing.T) (*LoginHandler, *Service, *applettest.Server) {
+	srv := applettest.NewServer("wx0000000000000000", "secret")
+
+	wechatAPI, err := api.NewWechatAPI("wx0000000000000000",
+		api.WithAppKey("secret"),
+		api.WithBaseURL(srv.URL),
+		api.WithRetry(0, 0),
+		api.WithLogger(log.New(ioutil.Discard, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc, err := NewService(wechatAPI, NewMemoryStore(), testSecret, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewLoginHandler(svc, nil, nil).SetLogger(log.New(ioutil.Discard, "", 0)), svc, srv
+}
+
+func login(handler http.Handler, method, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, "/login", strings.NewReader(body)))
+	return recorder
+}
+
+func TestLoginHandler(t *testing.T) {
+	handler, svc, srv := newLoginHandler(t)
+	defer srv.Close()
+	srv.AddSession("code", &api.RespCode2Session{OpenID: "openid", SessionKey: "key"})
+
+	rec := login(handler, http.MethodPost, `{"code":"code"}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"openid":"openid"`) {
+		t.Fatalf("login got %d %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "key") {
+		t.Fatalf("login response leaked the session key: %s", rec.Body.String())
+	}
+
+	resp := &LoginResponse{}
+	json.Unmarshal(rec.Body.Bytes(), resp)
+	if session, err := svc.Verify(resp.Token); err != nil || session.OpenID != "openid" {
+		t.Fatalf("issued token got %+v %v", session, err)
+	}
+}
+
+func TestLoginHandlerBadRequest(t *testing.T) {
+	handler, _, srv := newLoginHandler(t)
+	defer srv.Close()
+
+	if rec := login(handler, http.MethodGet, ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET got %d", rec.Code)
+	}
+	for _, body := range []string{"", "{", `{"code":""}`} {
+		if rec := login(handler, http.MethodPost, body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("body %q got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestLoginHandlerUpstreamError(t *testing.T) {
+	handler, _, srv := newLoginHandler(t)
+	defer srv.Close()
+
+	srv.Script("/sns/jscode2session",
+		applettest.Script{ErrCode: 40029, ErrMsg: "invalid code"},
+		applettest.Script{Status: http.StatusInternalServerError},
+		applettest.Script{ErrCode: -1, ErrMsg: "system error"})
+
+	for _, status := range []int{http.StatusUnauthorized, http.StatusBadGateway, http.StatusBadGateway} {
+		if rec := login(handler, http.MethodPost, `{"code":"code"}`); rec.Code != status {
+			t.Fatalf("want %d got %d", status, rec.Code)
+		}
+	}
+
+	srv.Close()
+	if rec := login(handler, http.MethodPost, `{"code":"code"}`); rec.Code != http.StatusBadGateway {
+		t.Fatalf("network error got %d", rec.Code)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	svc := newService(t)
+	token, _ := svc.Issue(&Session{OpenID: "openid"})
+	handler := Middleware(svc)(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte(OpenIDFromContext(request.Context())))
+	}))
+
+	for authorization, status := range map[string]int{
+		"Bearer " + token: http.StatusOK,
+		"bearer " + token: http.StatusOK,
+		"":                http.StatusUnauthorized,
+		token:             http.StatusUnauthorized,
+		"Bearer bad":      http.StatusUnauthorized,
+	} {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("Authorization", authorization)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, request)
+		if rec.Code != status {
+			t.Fatalf("authorization %q got %d", authorization, rec.Code)
+		}
+		if status == http.StatusOK && rec.Body.String() != "openid" {
+			t.Fatalf("context openid got %q", rec.Body.String())
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	for authorization, want := range map[string]string{
+		"Bearer abc":   "abc",
+		"BEARER abc ":  "abc",
+		"Bearer ":      "",
+		"Bearer":       "",
+		"Bearerabc":    "",
+		"Basic abc":    "",
+		"Token abc":    "",
+		"Bearer   abc": "abc",
+	} {
+		token, ok := bearerToken(authorization)
+		if token != want || ok != (want != "") {
+			t.Fatalf("authorization %q got %q %v", authorization, token, ok)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+// Package session 基于Code2Session的小程序登录会话
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/amazing-gao/applet/api"
+)
+
+var (
+	// ErrInvalidToken 会话令牌格式或签名错误
+	ErrInvalidToken = errors.New("invalid session token")
+	// ErrTokenExpired 会话令牌已过期
+	ErrTokenExpired = errors.New("session token expired")
+	// ErrSessionNotFound 会话不存在、已注销或已被重新登录替换
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrWeakSecret 签名秘钥过短
+	ErrWeakSecret = fmt.Errorf("session secret must be at least %d bytes", MinSecretSize)
+)
+
+const (
+	// MinSecretSize 签名秘钥的最小长度，与HS256的输出长度一致
+	MinSecretSize = 32
+
+	// errCodeBusy 微信系统繁忙
+	errCodeBusy = -1
+)
+
+type (
+	// Session 用户登录会话，SessionKey只保存在服务端
+	// 每个用户只保留最近一次登录的会话，ID与令牌的jti一致
+	Session struct {
+		ID         string    `json:"id"`
+		OpenID     string    `json:"openid"`
+		UnionID    string    `json:"unionid"`
+		SessionKey string    `json:"session_key"`
+		CreatedAt  time.Time `json:"created_at"`
+	}
+
+	// Service 小程序登录服务
+	// 使用code换取会话并保存，签发HS256的JWT作为客户端的会话令牌
+	// examples:
+	// svc, err := session.NewService(app.API, session.NewMemoryStore(), []byte("your secret at least 32 bytes.."), 7*24*time.Hour)
+	// token, sess, err := svc.Login(code)
+	// sess, err := svc.Verify(token)
+	Service struct {
+		api    *api.WechatAPI
+		store  Store
+		secret []byte
+		ttl    time.Duration
+	}
+
+	// UpstreamError 调用Code2Session失败，例如网络错误、5xx响应或微信系统繁忙，区别于code无效等业务错误
+	UpstreamError struct {
+		Err error
+	}
+
+	// Claims 会话令牌的内容
+	Claims struct {
+		ID        string `json:"jti"` // 会话ID，每次签发重新生成
+		Subject   string `json:"sub"` // openid
+		IssuedAt  int64  `json:"iat"`
+		ExpiresAt int64  `json:"exp"`
+	}
+)
+
+// jwtHeader HS256的JWT头，base64url编码
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// NewService 新建一个登录服务，ttl为会话及令牌的有效期
+// secret为令牌的签名秘钥，不能少于MinSecretSize字节，建议使用随机生成的秘钥
+func NewService(wechatAPI *api.WechatAPI, store Store, secret []byte, ttl time.Duration) (*Service, error) {
+	if len(secret) < MinSecretSize {
+		return nil, ErrWeakSecret
+	}
+
+	return &Service{
+		api:    wechatAPI,
+		store:  store,
+		secret: secret,
+		ttl:    ttl,
+	}, nil
+}
+
+// Login 使用wx.login获取的code换取会话，保存后签发会话令牌
+// 微信接口不可用时返回*UpstreamError
+func (svc *Service) Login(code string) (string, *Session, error) {
+	respData, resp, errs := svc.api.Code2Session(code)
+	if len(errs) != 0 {
+		return "", nil, &UpstreamError{Err: errs[0]}
+	} else if resp.ErrCode == errCodeBusy {
+		return "", nil, &UpstreamError{Err: fmt.Errorf("code2session errcode:%d errmsg:%s", resp.ErrCode, resp.ErrMsg)}
+	} else if resp.ErrCode != 0 {
+		return "", nil, fmt.Errorf("code2session errcode:%d errmsg:%s", resp.ErrCode, resp.ErrMsg)
+	}
+
+	session := &Session{
+		OpenID:     respData.OpenID,
+		UnionID:    respData.UnionID,
+		SessionKey: respData.SessionKey,
+		CreatedAt:  time.Now(),
+	}
+
+	token, err := svc.Issue(session)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, session, nil
+}
+
+func (err *UpstreamError) Error() string {
+	return "code2session upstream error: " + err.Err.Error()
+}
+
+// Issue 为会话生成新的ID，保存后签发会话令牌，该用户之前签发的令牌随之失效
+func (svc *Service) Issue(session *Session) (string, error) {
+	id, err := randomID()
+	if err != nil {
+		return "", err
+	}
+
+	session.ID = id
+	if err := svc.store.Set(session, svc.ttl); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims, err := json.Marshal(&Claims{
+		ID:        id,
+		Subject:   session.OpenID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(svc.ttl).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	payload := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	return payload + "." + svc.sign(payload), nil
+}
+
+// Verify 校验会话令牌的签名和有效期，并获取对应的会话
+// 令牌的jti与当前会话不一致时（已注销后重新登录）返回ErrSessionNotFound
+func (svc *Service) Verify(token string) (*Session, error) {
+	claims, err := svc.Parse(token)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := svc.store.Get(claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(session.ID), []byte(claims.ID)) {
+		return nil, ErrSessionNotFound
+	}
+
+	return session, nil
+}
+
+// Parse 校验会话令牌的签名和有效期，不查询会话存储器
+func (svc *Service) Parse(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !hmac.Equal(signature, svc.mac(parts[0]+"."+parts[1])) {
+		return nil, ErrInvalidToken
+	}
+
+	rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims := &Claims{}
+	if err = json.Unmarshal(rawClaims, claims); err != nil || claims.Subject == "" || claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+
+	return claims, nil
+}
+
+// Logout 注销会话，已签发的令牌随之失效
+func (svc *Service) Logout(openid string) error {
+	return svc.store.Delete(openid)
+}
+
+// randomID 生成随机的会话ID
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func (svc *Service) sign(payload string) string {
+	return base64.RawURLEncoding.EncodeToString(svc.mac(payload))
+}
+
+func (svc *Service) mac(payload string) []byte {
+	h := hmac.New(sha256.New, svc.secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
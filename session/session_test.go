@@ -0,0 +1,107 @@
+package session
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func newService(t *testing.T) *Service {
+	svc, err := NewService(nil, NewMemoryStore(), testSecret, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return svc
+}
+
+func TestNewServiceMinSecretSize(t *testing.T) {
+	if _, err := NewService(nil, NewMemoryStore(), testSecret[:MinSecretSize-1], time.Hour); err != ErrWeakSecret {
+		t.Fatalf("short secret got %v", err)
+	}
+	if _, err := NewService(nil, NewMemoryStore(), testSecret[:MinSecretSize], time.Hour); err != nil {
+		t.Fatalf("secret of MinSecretSize got %v", err)
+	}
+}
+
+func TestIssueVerify(t *testing.T) {
+	svc := newService(t)
+
+	token, err := svc.Issue(&Session{OpenID: "openid", SessionKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := svc.Verify(token)
+	if err != nil || session.OpenID != "openid" || session.SessionKey != "key" {
+		t.Fatalf("verify got %+v %v", session, err)
+	}
+}
+
+func TestVerifyTamperedToken(t *testing.T) {
+	svc := newService(t)
+	token, _ := svc.Issue(&Session{OpenID: "openid"})
+	parts := strings.Split(token, ".")
+
+	// 替换payload但保留原签名
+	claims, _ := json.Marshal(&Claims{ID: "id", Subject: "other", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	forged := parts[0] + "." + base64.RawURLEncoding.EncodeToString(claims) + "." + parts[2]
+
+	// 其他秘钥签发的令牌
+	other, _ := NewService(nil, NewMemoryStore(), []byte(strings.Repeat("x", MinSecretSize)), time.Hour)
+	foreign, _ := other.Issue(&Session{OpenID: "openid"})
+
+	for _, bad := range []string{"", "a.b", forged, parts[0] + "." + parts[1] + ".bad", foreign} {
+		if _, err := svc.Verify(bad); err != ErrInvalidToken {
+			t.Fatalf("token %q got %v", bad, err)
+		}
+	}
+}
+
+func TestVerifyExpiredToken(t *testing.T) {
+	svc := newService(t)
+	svc.store.Set(&Session{ID: "id", OpenID: "openid"}, time.Hour)
+
+	claims, _ := json.Marshal(&Claims{ID: "id", Subject: "openid", IssuedAt: time.Now().Add(-2 * time.Hour).Unix(), ExpiresAt: time.Now().Add(-time.Hour).Unix()})
+	payload := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(claims)
+	if _, err := svc.Verify(payload + "." + svc.sign(payload)); err != ErrTokenExpired {
+		t.Fatalf("expired token got %v", err)
+	}
+}
+
+func TestLogoutRevokesOlderTokens(t *testing.T) {
+	svc := newService(t)
+
+	first, _ := svc.Issue(&Session{OpenID: "openid"})
+	if err := svc.Logout("openid"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Verify(first); err != ErrSessionNotFound {
+		t.Fatalf("token after logout got %v", err)
+	}
+
+	// 重新登录后，注销前签发的令牌仍然无效
+	second, _ := svc.Issue(&Session{OpenID: "openid"})
+	if _, err := svc.Verify(first); err != ErrSessionNotFound {
+		t.Fatalf("old token after relogin got %v", err)
+	}
+	if _, err := svc.Verify(second); err != nil {
+		t.Fatalf("new token got %v", err)
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	store := NewMemoryStore()
+	store.Set(&Session{OpenID: "openid"}, -time.Second)
+
+	if _, err := store.Get("openid"); err != ErrSessionNotFound {
+		t.Fatalf("expired session got %v", err)
+	}
+	if len(store.sessions) != 0 {
+		t.Fatal("expired session was not removed on read")
+	}
+}
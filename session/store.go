@@ -0,0 +1,94 @@
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// Store 会话存储器，可基于redis等实现多实例共享
+	Store interface {
+		Get(openid string) (*Session, error)
+		Set(session *Session, ttl time.Duration) error
+		Delete(openid string) error
+	}
+
+	// MemoryStore 基于内存的会话存储器，仅适用于单实例部署
+	// 过期会话在读取时删除，未再读取的过期会话在Set时定期清理
+	MemoryStore struct {
+		sessions  map[string]*memorySession
+		lastSweep time.Time
+		locker    *sync.RWMutex
+	}
+
+	memorySession struct {
+		session  *Session
+		expireAt time.Time
+	}
+)
+
+const (
+	// memorySweepInterval 内存会话存储器清理过期会话的最小间隔
+	memorySweepInterval = time.Minute
+)
+
+// NewMemoryStore 新建一个内存会话存储器
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions:  map[string]*memorySession{},
+		lastSweep: time.Now(),
+		locker:    &sync.RWMutex{},
+	}
+}
+
+// Get 获取会话，不存在或已过期时返回ErrSessionNotFound，已过期的会话会被删除
+func (store *MemoryStore) Get(openid string) (*Session, error) {
+	store.locker.RLock()
+	item, ok := store.sessions[openid]
+	store.locker.RUnlock()
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	if time.Now().After(item.expireAt) {
+		store.locker.Lock()
+		// 删除前确认未被重新保存
+		if store.sessions[openid] == item {
+			delete(store.sessions, openid)
+		}
+		store.locker.Unlock()
+		return nil, ErrSessionNotFound
+	}
+
+	return item.session, nil
+}
+
+// Set 保存会话，距上次清理超过memorySweepInterval时清理过期会话
+func (store *MemoryStore) Set(session *Session, ttl time.Duration) error {
+	defer store.locker.Unlock()
+	store.locker.Lock()
+
+	now := time.Now()
+	if now.Sub(store.lastSweep) >= memorySweepInterval {
+		store.lastSweep = now
+		for openid, item := range store.sessions {
+			if now.After(item.expireAt) {
+				delete(store.sessions, openid)
+			}
+		}
+	}
+
+	store.sessions[session.OpenID] = &memorySession{session: session, expireAt: now.Add(ttl)}
+
+	return nil
+}
+
+// Delete 删除会话
+func (store *MemoryStore) Delete(openid string) error {
+	defer store.locker.Unlock()
+	store.locker.Lock()
+
+	delete(store.sessions, openid)
+
+	return nil
+}